@@ -3,11 +3,10 @@ package auth
 import (
 	"errors"
 	"fmt"
-	"net/url"
-	"strings"
+	"net/http"
 
-	"github.com/go-chassis/foundation/httpclient"
 	"github.com/go-chassis/foundation/security"
+	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis/v2/security/cipher"
 	"github.com/go-chassis/openlog"
 )
@@ -16,6 +15,12 @@ const (
 	HeaderServiceAk      = "X-Service-AK"
 	HeaderServiceShaAKSK = "X-Service-ShaAKSK"
 	HeaderServiceProject = "X-Service-Project"
+	HeaderSecurityToken  = "X-Security-Token"
+
+	// SignerShaAKSK sets X-Service-AK, X-Service-ShaAKSK and X-Service-Project headers
+	SignerShaAKSK = "shaAKSK"
+	// SignerAPIG signs request with SDK-HMAC-SHA256
+	SignerAPIG = "apig"
 
 	CipherRootEnv   = "CIPHER_ROOT"
 	KeytoolAkskFile = "certificate.yaml"
@@ -23,26 +28,44 @@ const (
 	keyAKV2      = "servicecomb.credentials.accessKey"
 	keySKV2      = "servicecomb.credentials.secretKey"
 	keyProjectV2 = "servicecomb.credentials.project"
+	keySigner    = "servicecomb.credentials.signer"
 
 	keyAK      = "cse.credentials.accessKey"
 	keySK      = "cse.credentials.secretKey"
 	keyProject = "cse.credentials.project"
+	keyCipher  = "cse.credentials.akskCustomCipher"
 )
 
 //ErrAuthConfNotExist means the auth config not exist
 var ErrAuthConfNotExist = errors.New("auth config is not exist")
 
+// LoadAuth loads auth of servicecomb.credentials.type, ak/sk by default.
+// ak/sk auth keeps watching the credential sources,
+// so that rotated keys take effect without restarting the service.
+// if servicecomb.credentials.validate is true, the loaded ak/sk is validated by the cloud service
+// of servicecomb.credentials.validateTarget before it is used
 func LoadAuth() error {
-	err := LoadAkskAuth()
-	if err == nil {
+	if err := loadEndpointSigner(); err != nil {
+		return err
+	}
+	// a credential appearing later is signed by this sign func as well
+	installSignRequest()
+	if t := archaius.GetString(keyType, TypeAKSK); t != TypeAKSK {
+		return loadTokenAuth(t)
+	}
+	a, err := newAkskAuth()
+	if err = validateOnStartup(a, err); err == nil {
+		useAkskAuth(a)
 		openlog.Info("huawei cloud auth enabled")
+		watchAkskAuth()
 		return nil
 	}
 	if err != ErrAuthConfNotExist {
-		openlog.Error(fmt.Sprintf("load ak sk failed: %s", err))
+		openlog.Error(fmt.Sprintf("load ak sk failed: %s", Redact(err.Error())))
 		return err
 	}
 	openlog.Info("no credential found")
+	watchAkskAuth()
 	return nil
 }
 
@@ -58,46 +81,145 @@ func getAkskCustomCipher(name string) (security.Cipher, error) {
 	return cipherPlugin, nil
 }
 
-func getProjectFromURI(rawurl string) (string, error) {
-	errGetProjectFailed := errors.New("get project from CSE uri failed")
-	// rawurl: https://cse.cn-north-1.myhwclouds.com:443
-	if rawurl == "" {
-		return "", fmt.Errorf("%v, CSE uri empty", errGetProjectFailed)
+// decryptSecretKey decrypts sk with akskCustomCipher, returns sk as it is if no cipher configured.
+// the credential is registered in scope to be redacted from logs, it replaces the credential registered before,
+// a credential failed to decrypt is added instead, because the old one may still be in use
+func decryptSecretKey(scope string, c *Credential) (string, error) {
+	values := []sensitiveValue{identifier(c.AccessKey), secret(c.SecretKey), secret(c.SecurityToken)}
+	res, err := decryptWithCipher(c)
+	if err != nil {
+		addSensitive(scope, values...)
+		emitCredentialEvent(CredentialEvent{
+			Type:      EventDecryptionFailed,
+			AccessKey: maskIfSet(c.AccessKey),
+			Cipher:    c.AkskCustomCipher,
+			Error:     err.Error(),
+		})
+		return "", err
 	}
+	registerSensitive(scope, append(values, secret(res))...)
+	return res, nil
+}
 
-	u, err := url.Parse(rawurl)
+func decryptWithCipher(c *Credential) (string, error) {
+	cipherPluginName := c.AkskCustomCipher
+	if cipherPluginName == "" {
+		return c.SecretKey, nil
+	}
+	cipherPlugin, err := getAkskCustomCipher(cipherPluginName)
 	if err != nil {
-		return "", fmt.Errorf("%v, %v", errGetProjectFailed, err)
+		return "", err
 	}
-	parts := strings.Split(u.Host, ".")
-	if len(parts) != 4 {
-		openlog.Info("CSE uri contains no project")
-		return "", nil
+	res, err := cipherPlugin.Decrypt(c.SecretKey)
+	if err != nil {
+		return "", fmt.Errorf("decrypt sk failed %v", err)
 	}
-	return parts[1], nil
+	return res, nil
 }
 
 // LoadAkskAuth gets the Authentication Mode ak/sk
 func LoadAkskAuth() error {
-	c, err := getAkskConfig()
+	a, err := newAkskAuth()
 	if err != nil {
 		return err
 	}
-	openlog.Info(fmt.Sprintf("huawei cloud auth AK: %s, project: %s", c.AccessKey, c.Project))
-	plainSk := c.SecretKey
-	cipherPluginName := c.AkskCustomCipher
-	if cipherPluginName != "" {
-		cipherPlugin, err := getAkskCustomCipher(cipherPluginName)
-		if err != nil {
-			return err
+	useAkskAuth(a)
+	return nil
+}
+
+// useAkskAuth signs requests with a from now on
+func useAkskAuth(a *akskAuth) {
+	currentAuth.Store(a)
+	emitCredentialEvent(a.event(EventLoaded))
+	stopTokenAuth()
+	setSignRequest(signWithCurrentAuth)
+	installSignRequest()
+	scheduleRefresh(a)
+	scheduleProbe()
+}
+
+// newAkskAuth reads the credential config, decrypts sk and builds the sign func
+func newAkskAuth() (*akskAuth, error) {
+	c, provider, err := getAkskConfig()
+	if err != nil {
+		return nil, err
+	}
+	a, err := newAkskAuthOf(scopeService, c, provider)
+	if err != nil {
+		return nil, err
+	}
+	if c.Secondary != nil && c.Secondary.AccessKey != "" {
+		if a.secondary, err = newAkskAuthOf(scopeServiceSecondary, c.secondaryCredential(), provider); err != nil {
+			return nil, fmt.Errorf("secondary credential: %w", err)
 		}
-		res, err := cipherPlugin.Decrypt(c.SecretKey)
+	}
+	return a, nil
+}
+
+// newAkskAuthOf decrypts sk of c and builds the sign func
+func newAkskAuthOf(scope string, c *Credential, provider string) (*akskAuth, error) {
+	plainSk, err := decryptSecretKey(scope, c)
+	if err != nil {
+		return nil, err
+	}
+	shaAKSK, err := genShaAKSK(plainSk, c.AccessKey)
+	if err != nil {
+		return nil, err
+	}
+	resolveProjectID(c, plainSk)
+	signer := archaius.GetString(keySigner, SignerShaAKSK)
+	sign, err := newSignFuncOf(signer, c, plainSk)
+	if err != nil {
+		return nil, err
+	}
+	return &akskAuth{
+		provider:      provider,
+		signer:        signer,
+		ak:            c.AccessKey,
+		shaAKSK:       shaAKSK,
+		project:       c.Project,
+		projectID:     c.ProjectID,
+		securityToken: c.SecurityToken,
+		expiresAt:     c.ExpiresAt,
+		sign:          sign,
+		validate: func() error {
+			return validateCredential(c, plainSk)
+		},
+		lookupProject: func(name string) (string, error) {
+			return lookupProjectID(c, plainSk, name)
+		},
+	}, nil
+}
+
+// newSignFunc creates sign func according to servicecomb.credentials.signer
+func newSignFunc(c *Credential, plainSk string) (SignRequest, error) {
+	return newSignFuncOf(archaius.GetString(keySigner, SignerShaAKSK), c, plainSk)
+}
+
+func newSignFuncOf(signer string, c *Credential, plainSk string) (SignRequest, error) {
+	switch signer {
+	case SignerShaAKSK:
+		return GetTemporaryShaAKSKSignFunc(c.AccessKey, plainSk, c.SecurityToken, c.Project)
+	case SignerAPIG:
+		sign, err := GetAPIGSignFunc(c.AccessKey, plainSk, c.SecurityToken)
 		if err != nil {
-			return fmt.Errorf("decrypt sk failed %v", err)
+			return nil, err
 		}
-		plainSk = res
+		// project id is signed as well
+		return func(r *http.Request) error {
+			id, err := projectIDOf(r, c, plainSk)
+			if err != nil {
+				return err
+			}
+			if id != "" {
+				if r.Header == nil {
+					r.Header = make(http.Header)
+				}
+				r.Header.Set(HeaderProjectID, id)
+			}
+			return sign(r)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown signer [%s]", signer)
 	}
-
-	httpclient.SignRequest, err = GetShaAKSKSignFunc(c.AccessKey, plainSk, c.Project)
-	return err
 }
@@ -3,6 +3,7 @@ package auth_test
 import (
 	"fmt"
 	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis-cloud/auth"
 	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/config"
@@ -14,6 +15,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func testWriteFile(t *testing.T, name string, ak, sk, project, cipher string) {
@@ -91,6 +93,10 @@ func Test_loadAkskAuth(t *testing.T) {
 	}
 
 	config.InitArchaius()
+	// archaius initialized by a former run of this test reads the file by its watcher
+	assert.Eventually(t, func() bool {
+		return archaius.GetString("cse.credentials.accessKey", "") == ak
+	}, 3*time.Second, 10*time.Millisecond)
 	config.GlobalDefinition = &model.GlobalCfg{}
 	config.GlobalDefinition.ServiceComb.Registry.Address = uriWithProjectCnNorth
 	err = auth.LoadAkskAuth()
@@ -19,74 +19,68 @@ package auth
 
 import (
 	"errors"
-	"github.com/go-chassis/go-archaius"
-	"github.com/go-chassis/go-chassis-cloud/provider/huawei/env"
-	"github.com/go-chassis/go-chassis/v2/core/common"
-	"github.com/go-chassis/go-chassis/v2/core/config"
-	"github.com/go-chassis/go-chassis/v2/core/config/model"
 	"gopkg.in/yaml.v2"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 )
 
-func getAkskConfig() (*model.CredentialStruct, error) {
-	// 1, if env CIPHER_ROOT exists, read ${CIPHER_ROOT}/certificate.yaml
-	// 2, if env CIPHER_ROOT not exists, read chassis config
-	var akskFile string
-	if v, exist := os.LookupEnv(CipherRootEnv); exist {
-		p := filepath.Join(v, KeytoolAkskFile)
-		if _, err := os.Stat(p); err != nil {
-			if !os.IsNotExist(err) {
-				return nil, err
-			}
-		} else {
-			akskFile = p
-		}
+var errEmptyAKSK = errors.New("ak or sk is empty")
+
+// getAkskConfig returns credential and the name of provider it comes from
+func getAkskConfig() (*Credential, string, error) {
+	c, provider, err := retrieveAkskConfig(nil)
+	if err != nil {
+		return nil, provider, err
+	}
+	c.Project, err = resolveProject(c.Project)
+	if err != nil {
+		return nil, provider, err
 	}
+	return c, provider, nil
+}
 
-	c := &model.CredentialStruct{}
-	if akskFile == "" {
-		c.AccessKey = archaius.GetString(keyAKV2, archaius.GetString(keyAK, ""))
-		c.SecretKey = archaius.GetString(keySKV2, archaius.GetString(keySK, ""))
-		c.Project = archaius.GetString(keyProjectV2, archaius.GetString(keyProject, ""))
-		c.AkskCustomCipher = archaius.GetString(common.AKSKCustomCipher, archaius.GetString("cse.credentials.akskCustomCipher", ""))
-	} else {
-		yamlContent, err := ioutil.ReadFile(akskFile)
-		if err != nil {
-			return nil, err
-		}
-		globalConf := &model.GlobalCfg{}
-		err = yaml.Unmarshal(yamlContent, globalConf)
-		if err != nil {
-			return nil, err
-		}
-		c = &(globalConf.ServiceComb.Credentials)
+// retrieveAkskConfig walks the provider chain, by default:
+// 1, if env CIPHER_ROOT exists, read ${CIPHER_ROOT}/certificate.yaml
+// 2, if env CIPHER_ROOT not exists, read chassis config
+// 3, if no static credential, fetch temporary credential from instance metadata.
+// the project of credential is not resolved yet
+func retrieveAkskConfig(trace providerTrace) (*Credential, string, error) {
+	c, provider, err := retrieveCredential(trace)
+	if err != nil {
+		return nil, provider, err
 	}
 	if c.AccessKey == "" && c.SecretKey == "" {
-		return nil, ErrAuthConfNotExist
+		return nil, provider, ErrAuthConfNotExist
 	}
 	if c.AccessKey == "" || c.SecretKey == "" {
-		return nil, errors.New("ak or sk is empty")
+		return nil, provider, errEmptyAKSK
 	}
+	return c, provider, nil
+}
+
+// resolveProject returns the project to use
+func resolveProject(project string) (string, error) {
+	l, err := ResolveLocation(project)
+	if err != nil {
+		return "", err
+	}
+	return l.Project, nil
+}
 
-	// 1, use project of env PAAS_PROJECT_NAME
-	// 2, use project in the credential config
-	// 3, use project in cse uri contain
-	// 4, use project "default"
-	if v := env.ProjectName(); v != "" {
-		c.Project = v
+// unmarshalCredential reads credential under servicecomb.credentials,
+// falls back to the legacy cse.credentials
+func unmarshalCredential(yamlContent []byte) (*Credential, error) {
+	conf := &struct {
+		ServiceComb struct {
+			Credentials Credential `yaml:"credentials"`
+		} `yaml:"servicecomb"`
+		CSE struct {
+			Credentials Credential `yaml:"credentials"`
+		} `yaml:"cse"`
+	}{}
+	if err := yaml.Unmarshal(yamlContent, conf); err != nil {
+		return nil, err
 	}
-	if c.Project == "" {
-		project, err := getProjectFromURI(config.GetRegistratorAddress())
-		if err != nil {
-			return nil, err
-		}
-		if project != "" {
-			c.Project = project
-		} else {
-			c.Project = common.DefaultValue
-		}
+	if conf.ServiceComb.Credentials != (Credential{}) {
+		return &conf.ServiceComb.Credentials, nil
 	}
-	return c, nil
+	return &conf.CSE.Credentials, nil
 }
@@ -0,0 +1,110 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-chassis/openlog"
+)
+
+// CredentialEventType is what happened to a credential
+type CredentialEventType string
+
+// credential event types
+const (
+	EventLoaded           CredentialEventType = "loaded"
+	EventRotated          CredentialEventType = "rotated"
+	EventDecryptionFailed CredentialEventType = "decryptionFailed"
+	EventRefreshFailed    CredentialEventType = "refreshFailed"
+	EventPromoted         CredentialEventType = "promoted"
+)
+
+// CredentialEvent is an audit record of credential,
+// it is redacted by the same policy as logs, so it can be forwarded to other systems as it is
+type CredentialEvent struct {
+	Type CredentialEventType
+	Time time.Time
+	// CredentialType is aksk, token or rbac
+	CredentialType string
+	Provider       string
+	// AccessKey is masked, it is empty for token credentials
+	AccessKey string
+	// OldAccessKey is the masked access key before rotation
+	OldAccessKey string
+	Project      string
+	Cipher       string
+	Error        string
+}
+
+// CredentialEventListener receives credential events, it is called in the goroutine where the event happens,
+// so it must not block
+type CredentialEventListener func(e CredentialEvent)
+
+type eventSubscriber struct {
+	l CredentialEventListener
+}
+
+var (
+	subscribersLock sync.RWMutex
+	subscribers     []*eventSubscriber
+)
+
+// SubscribeCredentialEvents receives credential events until cancel is called
+func SubscribeCredentialEvents(l CredentialEventListener) (cancel func()) {
+	s := &eventSubscriber{l: l}
+	subscribersLock.Lock()
+	subscribers = append(subscribers, s)
+	subscribersLock.Unlock()
+	return func() {
+		subscribersLock.Lock()
+		defer subscribersLock.Unlock()
+		for i, v := range subscribers {
+			if v == s {
+				subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emitCredentialEvent logs e and sends it to subscribers
+func emitCredentialEvent(e CredentialEvent) {
+	e.Time = time.Now()
+	e.Error = Redact(e.Error)
+	tags := openlog.Tags{"event": string(e.Type)}
+	for k, v := range map[string]string{"credentialType": e.CredentialType, "provider": e.Provider, "ak": e.AccessKey, "oldAK": e.OldAccessKey,
+		"project": e.Project, "cipher": e.Cipher, "error": e.Error} {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+	msg := "huawei cloud credential " + string(e.Type)
+	if e.Error != "" {
+		openlog.Error(msg, openlog.WithTags(tags))
+	} else {
+		openlog.Info(msg, openlog.WithTags(tags))
+	}
+	subscribersLock.RLock()
+	list := subscribers
+	subscribersLock.RUnlock()
+	for _, s := range list {
+		s.l(e)
+	}
+}
@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEventRecorder struct {
+	mu     sync.Mutex
+	events []auth.CredentialEvent
+}
+
+func (r *testEventRecorder) record(e auth.CredentialEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *testEventRecorder) find(t auth.CredentialEventType, ak string) (auth.CredentialEvent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e.Type == t && e.AccessKey == ak {
+			return e, true
+		}
+	}
+	return auth.CredentialEvent{}, false
+}
+
+func TestSubscribeCredentialEvents(t *testing.T) {
+	r := &testEventRecorder{}
+	cancel := auth.SubscribeCredentialEvents(r.record)
+	defer cancel()
+
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "EVENTAK000000001", "eventsk1", "evp1", "")
+	assert.NoError(t, auth.LoadAuth())
+	e, ok := r.find(auth.EventLoaded, "EVEN****0001")
+	assert.True(t, ok)
+	assert.Equal(t, auth.TypeAKSK, e.CredentialType)
+	assert.Equal(t, auth.ProviderFile, e.Provider)
+	assert.Equal(t, "evp1", e.Project)
+	assert.False(t, e.Time.IsZero())
+
+	t.Log("rotate credential file")
+	testWriteFile(t, credentialFilePath, "EVENTAK000000002", "eventsk2", "evp1", "")
+	assert.Eventually(t, func() bool {
+		e, ok = r.find(auth.EventRotated, "EVEN****0002")
+		return ok
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "EVEN****0001", e.OldAccessKey)
+
+	t.Log("keep old credential if sk can not be decrypted")
+	testWriteFile(t, credentialFilePath, "EVENTAK000000003", "eventsk3", "evp1", "not-exist")
+	assert.Eventually(t, func() bool {
+		_, ok = r.find(auth.EventDecryptionFailed, "EVEN****0003")
+		return ok
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		e, ok = r.find(auth.EventRefreshFailed, "EVEN****0002")
+		return ok
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.Contains(t, e.Error, "not-exist")
+
+	cancel()
+	r.mu.Lock()
+	n := len(r.events)
+	r.mu.Unlock()
+	testWriteFile(t, credentialFilePath, "EVENTAK000000004", "eventsk4", "evp1", "")
+	assert.Eventually(t, func() bool {
+		return testSignedAk(t) == "EVENTAK000000004"
+	}, 3*time.Second, 50*time.Millisecond)
+	r.mu.Lock()
+	assert.Equal(t, n, len(r.events))
+	r.mu.Unlock()
+}
+
+func TestRedact(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	auth.ResetRedaction()
+	testWriteFile(t, credentialFilePath, "REDACTAK00000001", "redactsk00000001", "rdp1", "")
+	assert.NoError(t, auth.LoadAuth())
+
+	assert.Equal(t, "ak REDA****0001 is invalid, sk: ******",
+		auth.Redact("ak REDACTAK00000001 is invalid, sk: redactsk00000001"))
+	origin := errors.New("bad sk redactsk00000001")
+	err := auth.RedactError(origin)
+	assert.Equal(t, "bad sk ******", err.Error())
+	assert.True(t, errors.Is(err, origin))
+	assert.Nil(t, auth.RedactError(nil))
+
+	t.Run("rotated out credential is dropped", func(t *testing.T) {
+		testWriteFile(t, credentialFilePath, "REDACTAK00000002", "redactsk00000002", "rdp1", "")
+		assert.NoError(t, auth.LoadAuth())
+		assert.Equal(t, "ak REDA****0002, sk: ******",
+			auth.Redact("ak REDACTAK00000002, sk: redactsk00000002"))
+		assert.Equal(t, "ak REDACTAK00000001, sk: redactsk00000001",
+			auth.Redact("ak REDACTAK00000001, sk: redactsk00000001"))
+	})
+}
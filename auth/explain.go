@@ -0,0 +1,195 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/provider/huawei/env"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+)
+
+// Explanation tells how the credential is resolved, it never carries secrets
+type Explanation struct {
+	// Steps are what happened in order
+	Steps []string
+	Type  string
+	// Provider is the provider whose credential wins
+	Provider      string
+	AccessKey     string
+	Region        string
+	Project       string
+	ProjectSource string
+	Cipher        string
+	// Signer tells how requests are signed, it is the token header for token types
+	Signer string
+}
+
+// step records what happened, registered secrets in it are redacted, like the ones in an error of provider
+func (e *Explanation) step(format string, args ...interface{}) {
+	e.Steps = append(e.Steps, Redact(fmt.Sprintf(format, args...)))
+}
+
+// Explain runs the same resolution as LoadAuth without using the credential,
+// the access key in explanation is masked.
+// the explanation is returned even if resolution fails, the error tells which step fails
+func Explain() (*Explanation, error) {
+	e := &Explanation{Type: archaius.GetString(keyType, TypeAKSK)}
+	e.step("credential type: %s", e.Type)
+	var err error
+	switch e.Type {
+	case TypeAKSK:
+		err = e.explainAKSK()
+	case TypeIAMToken:
+		err = e.explainIAMToken()
+	case TypeRBAC:
+		err = e.explainRBAC()
+	default:
+		err = fmt.Errorf("unknown credential type [%s]", e.Type)
+		e.step("%s", err)
+	}
+	return e, err
+}
+
+// explainAKSK explains the ak sk of provider chain and its signer
+func (e *Explanation) explainAKSK() error {
+	c, sk, err := e.explainCredential()
+	if err != nil {
+		return err
+	}
+	signer := archaius.GetString(keySigner, SignerShaAKSK)
+	if _, err := newSignFuncOf(signer, c, sk); err != nil {
+		e.step("create signer [%s] failed: %s", signer, err)
+		return err
+	}
+	e.Signer = signer
+	e.step("signer: %s", e.Signer)
+	return nil
+}
+
+// explainIAMToken explains how IAM token is fetched, the token itself is not fetched
+func (e *Explanation) explainIAMToken() error {
+	method := archaius.GetString(keyIAMMethod, IAMMethodPassword)
+	e.step("iam endpoint: %q, method: %s", archaius.GetString(keyIAMEndpoint, ""), method)
+	if method == IAMMethodAKSK {
+		// token request is signed with SDK-HMAC-SHA256 by ak sk
+		if _, _, err := e.explainCredential(); err != nil {
+			return err
+		}
+	} else {
+		e.step("iam domain: %q, user: %s", archaius.GetString(keyIAMDomain, ""), maskIfSet(archaius.GetString(keyIAMUser, "")))
+		if err := e.explainLocation(archaius.GetString(keyProjectV2, archaius.GetString(keyProject, ""))); err != nil {
+			return err
+		}
+	}
+	if _, err := newIAMTokenFetcher(); err != nil {
+		e.step("create iam token fetcher failed: %s", err)
+		return err
+	}
+	e.Signer = HeaderAuthToken
+	e.step("signer: iam token in %s", HeaderAuthToken)
+	return nil
+}
+
+// explainRBAC explains how service center token is fetched, and the ak sk signing requests together with the token,
+// ak sk is loaded by LoadAuth in rbac type as well, if there is one
+func (e *Explanation) explainRBAC() error {
+	e.step("account: %s, service center: %q", maskIfSet(archaius.GetString(keyAccountName, "")),
+		strings.Split(config.GetRegistratorAddress(), ",")[0])
+	if _, err := newRBACTokenFetcher(); err != nil {
+		e.step("create rbac token fetcher failed: %s", err)
+		return err
+	}
+	e.Signer = HeaderAuthorization
+	err := e.explainAKSK()
+	if err == ErrAuthConfNotExist {
+		e.step("signer: rbac token in %s, without ak sk", HeaderAuthorization)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	e.Signer = HeaderAuthorization + ", " + e.Signer
+	e.step("signer: rbac token in %s, and %s", HeaderAuthorization, e.Signer)
+	return nil
+}
+
+// explainCredential explains the resolution of ak sk, its project and the decryption of sk
+func (e *Explanation) explainCredential() (*Credential, string, error) {
+	e.step("provider chain: %v", ProviderChain())
+	c, provider, err := retrieveAkskConfig(func(name string, err error) {
+		switch err {
+		case nil:
+			e.step("provider [%s]: found credential", name)
+		case ErrAuthConfNotExist:
+			e.step("provider [%s]: no credential, try next", name)
+		default:
+			e.step("provider [%s]: failed: %s", name, err)
+		}
+	})
+	e.Provider = provider
+	switch {
+	case err == ErrAuthConfNotExist:
+		e.step("no credential found")
+		return nil, "", err
+	case err == errEmptyAKSK:
+		e.step("%s", err)
+		return nil, "", err
+	case err != nil:
+		return nil, "", err
+	}
+	e.AccessKey = MaskSecret(c.AccessKey)
+	e.step("ak: %s, temporary: %t", e.AccessKey, c.Temporary())
+	if c.Secondary != nil && c.Secondary.AccessKey != "" {
+		e.step("secondary ak: %s, sk set: %t", MaskSecret(c.Secondary.AccessKey), c.Secondary.SecretKey != "")
+	}
+	if err := e.explainLocation(c.Project); err != nil {
+		return nil, "", err
+	}
+	c.Project = e.Project
+
+	e.Cipher = c.AkskCustomCipher
+	sk, err := decryptSecretKey(scopeExplain, c)
+	if err != nil {
+		e.step("decrypt sk with cipher [%s] failed: %s", c.AkskCustomCipher, err)
+		return nil, "", err
+	}
+	if c.AkskCustomCipher == "" {
+		e.step("no cipher, sk is plain text")
+	} else {
+		e.step("decrypt sk with cipher [%s]: ok", c.AkskCustomCipher)
+	}
+	return c, sk, nil
+}
+
+// explainLocation explains how project and region are resolved from the configured project
+func (e *Explanation) explainLocation(project string) error {
+	e.step("env PAAS_PROJECT_NAME: %q, env PAAS_REGION_NAME: %q, configured project: %q, registry address: %q",
+		env.ProjectName(), env.RegionName(), project, config.GetRegistratorAddress())
+	l, err := ResolveLocation(project)
+	if err != nil {
+		e.step("resolve project failed: %s", err)
+		return err
+	}
+	e.Region, e.Project, e.ProjectSource = l.Region, l.Project, l.Source
+	e.step("project: %s, from %s: %s", l.Project, l.Source, l.Reason)
+	e.step("region: %s", l.Region)
+	return nil
+}
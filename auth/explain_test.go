@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "EXPLAINAK0000001", "es1", "ep1", "")
+	e, err := auth.Explain()
+	assert.NoError(t, err)
+	assert.Equal(t, auth.ProviderFile, e.Provider)
+	assert.Equal(t, "EXPL****0001", e.AccessKey)
+	assert.Equal(t, "ep1", e.Project)
+	assert.Equal(t, auth.ProjectFromCredential, e.ProjectSource)
+	for _, s := range e.Steps {
+		assert.NotContains(t, s, "EXPLAINAK0000001")
+		assert.NotContains(t, s, "es1")
+	}
+
+	t.Log("fails at decryption")
+	testWriteFile(t, credentialFilePath, "EXPLAINAK0000001", "es1", "ep1", "not-exist")
+	e, err = auth.Explain()
+	assert.Error(t, err)
+	assert.Equal(t, auth.ProviderFile, e.Provider)
+	assert.Contains(t, e.Steps[len(e.Steps)-1], "not-exist")
+}
+
+type testFailingProvider struct {
+	msg string
+}
+
+func (p *testFailingProvider) Name() string {
+	return "failing"
+}
+
+func (p *testFailingProvider) Retrieve() (*auth.Credential, error) {
+	return nil, errors.New(p.msg)
+}
+
+func TestExplain_ProviderError(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "EXPLAINAK0000002", "explain-secret-2", "ep2", "")
+	_, err := auth.Explain()
+	assert.NoError(t, err)
+
+	auth.InstallCredentialProvider(&testFailingProvider{msg: "resp: {\"secret\":\"explain-secret-2\"}"})
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "failing"))
+	defer archaius.Delete("servicecomb.credentials.providers")
+	e, err := auth.Explain()
+	assert.Error(t, err)
+	assert.Contains(t, e.Steps[len(e.Steps)-1], "provider [failing]: failed")
+	for _, s := range e.Steps {
+		assert.NotContains(t, s, "explain-secret-2", "errors of provider are redacted")
+	}
+}
+
+func TestExplain_TokenTypes(t *testing.T) {
+	testInitEnv(t)
+	defer archaius.Delete("servicecomb.credentials.type")
+
+	t.Run("iam token", func(t *testing.T) {
+		for k, v := range map[string]string{
+			"servicecomb.credentials.type":         auth.TypeIAMToken,
+			"servicecomb.credentials.iam.endpoint": "https://iam.example.com",
+			"servicecomb.credentials.iam.domain":   "explain-domain",
+			"servicecomb.credentials.iam.user":     "explain-user",
+			"servicecomb.credentials.iam.password": "explain-password",
+			"servicecomb.credentials.project":      "cn-north-4",
+			"servicecomb.credentials.iam.method":   auth.IAMMethodPassword,
+		} {
+			assert.NoError(t, archaius.Set(k, v))
+			defer archaius.Delete(k)
+		}
+		e, err := auth.Explain()
+		assert.NoError(t, err)
+		assert.Equal(t, auth.TypeIAMToken, e.Type)
+		assert.Equal(t, auth.HeaderAuthToken, e.Signer)
+		assert.Equal(t, "cn-north-4", e.Project)
+		assert.Empty(t, e.Provider, "ak sk is not used")
+		for _, s := range e.Steps {
+			assert.NotContains(t, s, "explain-password")
+			assert.NotContains(t, s, "provider chain")
+		}
+	})
+	t.Run("rbac", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("servicecomb.credentials.type", auth.TypeRBAC))
+		e, err := auth.Explain()
+		assert.Error(t, err, "no account")
+		assert.Equal(t, auth.TypeRBAC, e.Type)
+		assert.Contains(t, e.Steps[1], "account:")
+		assert.Contains(t, e.Steps[len(e.Steps)-1], "create rbac token fetcher failed")
+	})
+}
+
+func TestMaskSecret(t *testing.T) {
+	assert.Equal(t, "****", auth.MaskSecret(""))
+	assert.Equal(t, "****", auth.MaskSecret("12345678"))
+	assert.Equal(t, "1234****6789", auth.MaskSecret("1234x6789"))
+}
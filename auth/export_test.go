@@ -0,0 +1,36 @@
+package auth
+
+import "sync/atomic"
+
+// ResetProjectIDs drops project ids cached in memory, so that a test looks up again
+func ResetProjectIDs() {
+	projectIDs.Range(func(k, _ interface{}) bool {
+		projectIDs.Delete(k)
+		return true
+	})
+}
+
+// ResetMetadataProvider drops the credential cached by metadata provider
+func ResetMetadataProvider() {
+	providersLock.RLock()
+	p := providers[ProviderMetadata].(*metadataProvider)
+	providersLock.RUnlock()
+	p.mu.Lock()
+	p.cached = nil
+	p.mu.Unlock()
+}
+
+// ResetRedaction drops credentials registered by tests before
+func ResetRedaction() {
+	sensitiveLock.Lock()
+	defer sensitiveLock.Unlock()
+	sensitive = make(map[string][]sensitiveValue)
+	rebuildRedactor()
+}
+
+// ResetAkskAuth unloads ak sk, as if the process has just started
+func ResetAkskAuth() {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	currentAuth = atomic.Value{}
+}
@@ -0,0 +1,167 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	hws_cloud "github.com/huaweicse/auth/third_party/forked/datastream/aws"
+)
+
+// PayloadMode decides how the body of a request is signed
+type PayloadMode string
+
+// payload modes, set by WithPayloadMode or servicecomb.credentials.payload.mode
+const (
+	// PayloadSigned hashes the whole body, it is the default mode
+	PayloadSigned PayloadMode = "signed"
+	// PayloadUnsigned leaves the body out of signature, the body is sent as it is.
+	// the server must accept UNSIGNED-PAYLOAD in X-Sdk-Content-Sha256
+	PayloadUnsigned PayloadMode = "unsigned"
+	// PayloadStreaming hashes the body by streaming it from http.Request.GetBody, so it is never held in memory,
+	// the body is read twice, once to sign and once to send. the signature is same as the one of PayloadSigned,
+	// so any server accepting a signed payload accepts it. the request must have GetBody.
+	// signing the body chunk by chunk while it is sent is not supported, because APIG documents no such scheme
+	PayloadStreaming PayloadMode = "streaming"
+)
+
+// header and value of unsigned payload
+const (
+	HeaderContentSha256 = "X-Sdk-Content-Sha256"
+	UnsignedPayload     = "UNSIGNED-PAYLOAD"
+
+	keyPayloadThreshold = "servicecomb.credentials.payload.threshold"
+	keyPayloadMode      = "servicecomb.credentials.payload.mode"
+)
+
+// ErrBodyNotReplayable means the body of a request can not be streamed to sign, because it has no GetBody
+var ErrBodyNotReplayable = errors.New("body can not be read again to sign it, GetBody of request is nil")
+
+type payloadModeKey struct{}
+
+// WithPayloadMode returns a context whose requests are signed in mode,
+// it takes precedence over servicecomb.credentials.payload.threshold
+func WithPayloadMode(ctx context.Context, mode PayloadMode) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, payloadModeKey{}, mode)
+}
+
+// PayloadModeFromContext returns the mode saved by WithPayloadMode
+func PayloadModeFromContext(ctx context.Context) (PayloadMode, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	m, ok := ctx.Value(payloadModeKey{}).(PayloadMode)
+	return m, ok && m != ""
+}
+
+// payloadModeOf returns the mode of request context, or servicecomb.credentials.payload.mode
+// if body is larger than servicecomb.credentials.payload.threshold. a body of unknown length is taken as large
+func payloadModeOf(r *http.Request) PayloadMode {
+	if m, ok := PayloadModeFromContext(r.Context()); ok {
+		return m
+	}
+	if noBody(r) {
+		return PayloadSigned
+	}
+	threshold := archaius.GetInt64(keyPayloadThreshold, 0)
+	if threshold <= 0 || (r.ContentLength > 0 && r.ContentLength <= threshold) {
+		return PayloadSigned
+	}
+	return PayloadMode(archaius.GetString(keyPayloadMode, string(PayloadUnsigned)))
+}
+
+// signPayload signs r with s, the body is hashed in memory, hashed by streaming or left out according to its payload mode
+func signPayload(s *hws_cloud.Signer, r *http.Request) error {
+	switch mode := payloadModeOf(r); mode {
+	case PayloadSigned:
+		if noBody(r) || r.GetBody == nil {
+			return s.Sign(r)
+		}
+		// a replayable body is hashed without being copied into memory
+		return signStreaming(s, r)
+	case PayloadUnsigned:
+		r.Header.Set(HeaderContentSha256, UnsignedPayload)
+		return signWithPayloadHash(s, r, UnsignedPayload)
+	case PayloadStreaming:
+		if noBody(r) {
+			return s.Sign(r)
+		}
+		if r.GetBody == nil {
+			return ErrBodyNotReplayable
+		}
+		return signStreaming(s, r)
+	default:
+		return fmt.Errorf("unknown payload mode [%s]", mode)
+	}
+}
+
+// signStreaming signs r with the hash of a body got from r.GetBody, r.Body is left unread
+func signStreaming(s *hws_cloud.Signer, r *http.Request) error {
+	body, err := r.GetBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return err
+	}
+	return signWithPayloadHash(s, r, hex.EncodeToString(h.Sum(nil)))
+}
+
+// signWithPayloadHash is same as hws_cloud.Signer.Sign, but it uses payloadHash instead of reading body
+func signWithPayloadHash(s *hws_cloud.Signer, r *http.Request, payloadHash string) error {
+	t, err := time.Parse(hws_cloud.BasicDateFormat, r.Header.Get(hws_cloud.HeaderXDate))
+	if err != nil {
+		r.Header.Del(hws_cloud.HeaderDate)
+		t = signingTime()
+		r.Header.Set(hws_cloud.HeaderXDate, t.UTC().Format(hws_cloud.BasicDateFormat))
+	}
+	r.Header.Del(HeaderAuthorization)
+	// canonical headers sets host header, so it must be got before signed headers
+	canonicalHeaders := hws_cloud.CanonicalHeaders(r)
+	signedHeaders := hws_cloud.SignedHeaders(r)
+	canonicalRequest := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s", r.Method, hws_cloud.CanonicalURI(r),
+		hws_cloud.CanonicalQueryString(r), canonicalHeaders, signedHeaders, payloadHash)
+	scope := hws_cloud.CredentialScope(t, s.Region, s.Service)
+	key, err := hws_cloud.GenerateSigningKey(s.SecretKey, s.Region, s.Service, t)
+	if err != nil {
+		return err
+	}
+	signature, err := hws_cloud.SignStringToSign(hws_cloud.StringToSign(canonicalRequest, scope, t), key)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(HeaderAuthorization, hws_cloud.AuthHeaderValue(signature, s.AccessKey, scope, signedHeaders))
+	return nil
+}
+
+func noBody(r *http.Request) bool {
+	return r.Body == nil || r.Body == http.NoBody
+}
@@ -0,0 +1,90 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// unreadable fails the test if body is read while signing
+type unreadable struct {
+	t *testing.T
+}
+
+func (u unreadable) Read([]byte) (int, error) {
+	u.t.Error("body should not be read")
+	return 0, errors.New("unreadable")
+}
+
+func newUploadRequest(ctx context.Context, body io.Reader) *http.Request {
+	r, _ := http.NewRequest(http.MethodPut, "https://obs.example.com/bucket/object?uploads", body)
+	r.Header.Set(auth.HeaderSdkDate, "20201210T080000Z")
+	return r.WithContext(ctx)
+}
+
+func TestGetSignFunc_Payload(t *testing.T) {
+	sign, err := auth.GetSignFunc("ak", "sk", "")
+	assert.NoError(t, err)
+
+	t.Run("unsigned payload by context", func(t *testing.T) {
+		r := newUploadRequest(auth.WithPayloadMode(context.Background(), auth.PayloadUnsigned), unreadable{t})
+		assert.NoError(t, sign(r))
+		assert.Equal(t, auth.UnsignedPayload, r.Header.Get(auth.HeaderContentSha256))
+		assert.Contains(t, r.Header.Get(auth.HeaderAuthorization), "SignedHeaders=host;x-sdk-content-sha256;x-sdk-date")
+	})
+	t.Run("unsigned payload by threshold", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("servicecomb.credentials.payload.threshold", 10))
+		defer archaius.Delete("servicecomb.credentials.payload.threshold")
+
+		r := newUploadRequest(context.Background(), strings.NewReader("small"))
+		assert.NoError(t, sign(r))
+		assert.Empty(t, r.Header.Get(auth.HeaderContentSha256), "small body should be signed")
+
+		r = newUploadRequest(context.Background(), unreadable{t})
+		assert.NoError(t, sign(r))
+		assert.Equal(t, auth.UnsignedPayload, r.Header.Get(auth.HeaderContentSha256), "body of unknown length is large")
+	})
+	t.Run("unknown payload mode", func(t *testing.T) {
+		r := newUploadRequest(auth.WithPayloadMode(context.Background(), "zip"), strings.NewReader("x"))
+		assert.Error(t, sign(r))
+	})
+	t.Run("streaming payload", func(t *testing.T) {
+		data := strings.Repeat("large body ", 1024)
+		signed := newUploadRequest(context.Background(), ioutil.NopCloser(strings.NewReader(data)))
+		assert.Nil(t, signed.GetBody)
+		assert.NoError(t, sign(signed))
+
+		reads := 0
+		r := newUploadRequest(auth.WithPayloadMode(context.Background(), auth.PayloadStreaming), unreadable{t})
+		r.GetBody = func() (io.ReadCloser, error) {
+			reads++
+			return ioutil.NopCloser(strings.NewReader(data)), nil
+		}
+		assert.NoError(t, sign(r))
+		assert.Equal(t, 1, reads)
+		assert.Empty(t, r.Header.Get(auth.HeaderContentSha256))
+		assert.Equal(t, signed.Header.Get(auth.HeaderAuthorization), r.Header.Get(auth.HeaderAuthorization),
+			"same signature as signed payload")
+
+		r = newUploadRequest(auth.WithPayloadMode(context.Background(), auth.PayloadStreaming), unreadable{t})
+		assert.Equal(t, auth.ErrBodyNotReplayable, sign(r))
+	})
+	t.Run("signed payload streams a replayable body", func(t *testing.T) {
+		data := "replayable body"
+		r := newUploadRequest(context.Background(), bytes.NewBufferString(data))
+		assert.NotNil(t, r.GetBody)
+		assert.NoError(t, sign(r))
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, data, string(b), "body is left unread")
+	})
+}
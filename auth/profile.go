@@ -0,0 +1,337 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-archaius/event"
+	"github.com/go-chassis/openlog"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	keyProfiles  = "servicecomb.credentials.profiles"
+	keyEndpoints = "servicecomb.credentials.endpoints"
+)
+
+// ErrProfileNotExist means no credential profile has the name
+var ErrProfileNotExist = errors.New("credential profile not exist")
+
+var (
+	defaultSign           atomic.Value
+	currentEndpointSigner atomic.Value
+	watchEndpointOnce     sync.Once
+)
+
+// EndpointRule maps requests to a credential profile.
+// Pattern is a URL prefix if it has a scheme, like https://cse.cn-south-1.myhuaweicloud.com/v4,
+// otherwise it is a host glob, like *.cn-south-1.myhuaweicloud.com or 192.168.0.1:30100
+type EndpointRule struct {
+	Pattern string `yaml:"pattern"`
+	Profile string `yaml:"profile"`
+}
+
+func (e *EndpointRule) match(r *http.Request) bool {
+	return matchEndpoint(e.Pattern, r.URL)
+}
+
+// matchEndpoint matches u with a URL prefix or a host glob
+func matchEndpoint(pattern string, u *url.URL) bool {
+	if u == nil {
+		return false
+	}
+	if strings.Contains(pattern, "://") {
+		return strings.HasPrefix(u.Scheme+"://"+u.Host+u.Path, pattern)
+	}
+	if ok, _ := path.Match(pattern, u.Host); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, u.Hostname())
+	return ok
+}
+
+// EndpointSigner chooses credential profile for each request by endpoint rules
+type EndpointSigner struct {
+	profiles map[string]SignRequest
+	rules    []EndpointRule
+}
+
+// NewEndpointSigner creates an endpoint signer, every profile in rules must exist
+func NewEndpointSigner(profiles map[string]SignRequest, rules []EndpointRule) (*EndpointSigner, error) {
+	for _, rule := range rules {
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("empty pattern of profile [%s]", rule.Profile)
+		}
+		if _, ok := profiles[rule.Profile]; !ok {
+			return nil, fmt.Errorf("%w: [%s] of [%s]", ErrProfileNotExist, rule.Profile, rule.Pattern)
+		}
+	}
+	return &EndpointSigner{profiles: profiles, rules: rules}, nil
+}
+
+// Profile returns the profile of the first rule matching request
+func (s *EndpointSigner) Profile(r *http.Request) (string, bool) {
+	for i := range s.rules {
+		if s.rules[i].match(r) {
+			return s.rules[i].Profile, true
+		}
+	}
+	return "", false
+}
+
+// SignFunc signs request with the profile of its context or its endpoint, and with fallback if no rule matches
+func (s *EndpointSigner) SignFunc(fallback SignRequest) SignRequest {
+	return func(r *http.Request) error {
+		return s.sign(r, fallback)
+	}
+}
+
+func (s *EndpointSigner) sign(r *http.Request, fallback SignRequest) error {
+	if p, ok := ProfileFromContext(r.Context()); ok {
+		return s.signWithProfile(r, p)
+	}
+	if p, ok := s.Profile(r); ok {
+		return s.profiles[p](r)
+	}
+	if fallback == nil {
+		return nil
+	}
+	return fallback(r)
+}
+
+func (s *EndpointSigner) signWithProfile(r *http.Request, profile string) error {
+	if s != nil {
+		if sign, ok := s.profiles[profile]; ok {
+			return sign(r)
+		}
+	}
+	return fmt.Errorf("%w: [%s]", ErrProfileNotExist, profile)
+}
+
+// GetProfileSignFunc returns sign func of a profile in servicecomb.credentials.profiles,
+// it can be used by a client which always works in that profile
+func GetProfileSignFunc(name string) (SignRequest, error) {
+	profiles, err := getProfilesConfig()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: [%s]", ErrProfileNotExist, name)
+	}
+	return newProfileSignFunc(name, c)
+}
+
+// LoadEndpointSigner creates endpoint signer with servicecomb.credentials.profiles and servicecomb.credentials.endpoints
+func LoadEndpointSigner() (*EndpointSigner, error) {
+	rules, err := getEndpointsConfig()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := getProfilesConfig()
+	if err != nil {
+		return nil, err
+	}
+	signs := make(map[string]SignRequest, len(profiles))
+	for name, c := range profiles {
+		if signs[name], err = newProfileSignFunc(name, c); err != nil {
+			return nil, err
+		}
+	}
+	return NewEndpointSigner(signs, rules)
+}
+
+func newProfileSignFunc(name string, c *Credential) (SignRequest, error) {
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, fmt.Errorf("ak or sk of profile [%s] is empty", name)
+	}
+	sk, err := decryptSecretKey("profile."+name, c)
+	if err != nil {
+		return nil, fmt.Errorf("profile [%s]: %w", name, err)
+	}
+	// a profile works in its own project, the project of the service is used only if it has none
+	if c.Project == "" {
+		if c.Project, err = resolveProject(""); err != nil {
+			return nil, err
+		}
+	}
+	resolveProjectID(c, sk)
+	sign, err := newSignFunc(c, sk)
+	if err != nil {
+		return nil, err
+	}
+	return func(r *http.Request) error {
+		recordSignInfo(r, func(info *SignInfo) {
+			info.Type, info.Profile, info.AccessKey, info.Project = TypeAKSK, name, c.AccessKey, projectOf(r, c.Project)
+			if info.Project == c.Project {
+				info.ProjectID = c.ProjectID
+			}
+		})
+		return sign(r)
+	}, nil
+}
+
+// getProfilesConfig reads servicecomb.credentials.profiles.<name>.<field>,
+// profile names can not contain "."
+func getProfilesConfig() (map[string]*Credential, error) {
+	fields := make(map[string]map[string]interface{})
+	for k, v := range archaius.GetConfigs() {
+		if !strings.HasPrefix(k, keyProfiles+".") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(k, keyProfiles+"."), ".", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid profile config [%s]", k)
+		}
+		if fields[kv[0]] == nil {
+			fields[kv[0]] = make(map[string]interface{})
+		}
+		fields[kv[0]][kv[1]] = v
+	}
+	profiles := make(map[string]*Credential, len(fields))
+	for name, f := range fields {
+		b, err := yaml.Marshal(f)
+		if err != nil {
+			return nil, err
+		}
+		c := &Credential{}
+		if err := yaml.Unmarshal(b, c); err != nil {
+			return nil, fmt.Errorf("invalid profile [%s]: %v", name, err)
+		}
+		profiles[name] = c
+	}
+	return profiles, nil
+}
+
+func getEndpointsConfig() ([]EndpointRule, error) {
+	rules := make([]EndpointRule, 0)
+	v := archaius.Get(keyEndpoints)
+	if v == nil {
+		return rules, nil
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", keyEndpoints, err)
+	}
+	return rules, nil
+}
+
+// setSignRequest sets the sign func of requests matching no endpoint rule,
+// it is safe to call it concurrently with requests
+func setSignRequest(sign SignRequest) {
+	defaultSign.Store(sign)
+}
+
+// installSignRequest assigns signRequest to httpclient.SignRequest, which is a plain variable.
+// it is called by loading functions only, never by listeners or timers,
+// so that it is not written concurrently with requests, later changes only swap atomic values
+func installSignRequest() {
+	httpclient.SignRequest = signRequest
+}
+
+// profileIdentityHeaders returns ShaAKSK headers of a profile
+func profileIdentityHeaders(ctx context.Context, profile string) (map[string]string, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	s, _ := currentEndpointSigner.Load().(*EndpointSigner)
+	if err := s.signWithProfile(r, profile); err != nil {
+		return nil, err
+	}
+	if r.Header.Get(HeaderServiceShaAKSK) == "" {
+		return nil, fmt.Errorf("profile [%s] is not signed by %s", profile, SignerShaAKSK)
+	}
+	h := make(map[string]string)
+	for _, k := range []string{HeaderServiceAk, HeaderServiceShaAKSK, HeaderServiceProject, HeaderSecurityToken} {
+		if v := r.Header.Get(k); v != "" {
+			h[k] = v
+		}
+	}
+	return h, nil
+}
+
+// signRequest is assigned to httpclient.SignRequest,
+// it signs with the profile of request context or endpoint, or with the default sign func
+func signRequest(r *http.Request) error {
+	sign, _ := defaultSign.Load().(SignRequest)
+	if s, ok := currentEndpointSigner.Load().(*EndpointSigner); ok && s != nil {
+		return s.sign(r, sign)
+	}
+	if p, ok := ProfileFromContext(r.Context()); ok {
+		return (*EndpointSigner)(nil).signWithProfile(r, p)
+	}
+	if sign == nil {
+		return nil
+	}
+	return sign(r)
+}
+
+// loadEndpointSigner enables per endpoint credentials if any profile or endpoint rule is configured
+func loadEndpointSigner() error {
+	if _, err := reloadEndpointSigner(); err != nil {
+		return err
+	}
+	watchEndpointOnce.Do(func() {
+		if err := archaius.RegisterListener(&endpointListener{}, keyProfiles, keyEndpoints); err != nil {
+			openlog.Error("can not watch credential profiles: " + err.Error())
+		}
+	})
+	return nil
+}
+
+// reloadEndpointSigner swaps endpoint signer only, httpclient.SignRequest is installed by LoadAuth,
+// so that listeners never write it concurrently with requests
+func reloadEndpointSigner() (*EndpointSigner, error) {
+	s, err := LoadEndpointSigner()
+	if err != nil {
+		openlog.Error(fmt.Sprintf("load credential profiles failed: %s", Redact(err.Error())))
+		return nil, err
+	}
+	if len(s.rules) == 0 && len(s.profiles) == 0 {
+		currentEndpointSigner.Store((*EndpointSigner)(nil))
+		return nil, nil
+	}
+	currentEndpointSigner.Store(s)
+	openlog.Info(fmt.Sprintf("%d credential profiles, %d endpoint rules enabled", len(s.profiles), len(s.rules)))
+	return s, nil
+}
+
+// endpointListener reloads endpoint signer when profiles or endpoint rules change,
+// the old one is kept if the new config is invalid
+type endpointListener struct{}
+
+// Event implements event.Listener
+func (l *endpointListener) Event(e *event.Event) {
+	openlog.Info(fmt.Sprintf("credential profile config [%s] changed", e.Key))
+	reloadEndpointSigner()
+}
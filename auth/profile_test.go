@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadAuth_Profiles(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "pa1", "ps1", "pp1", "")
+	for k, v := range map[string]interface{}{
+		"servicecomb.credentials.profiles.south.accessKey": "south-ak",
+		"servicecomb.credentials.profiles.south.secretKey": "south-sk",
+		"servicecomb.credentials.profiles.south.project":   "cn-south-1",
+		"servicecomb.credentials.profiles.east.accessKey":  "east-ak",
+		"servicecomb.credentials.profiles.east.secretKey":  "east-sk",
+		"servicecomb.credentials.profiles.east.project":    "cn-east-2",
+		"servicecomb.credentials.endpoints": []interface{}{
+			map[string]interface{}{"pattern": "*.cn-south-1.example.com", "profile": "south"},
+			map[string]interface{}{"pattern": "https://cse.example.com/east", "profile": "east"},
+		},
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+	assert.NoError(t, auth.LoadAuth())
+
+	signed := func(url string) (string, string) {
+		r, err := http.NewRequest("GET", url, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, httpclient.SignRequest(r))
+		return r.Header.Get(auth.HeaderServiceAk), r.Header.Get(auth.HeaderServiceProject)
+	}
+	ak, project := signed("https://cse.cn-south-1.example.com:30100/v4/default/registry/microservices")
+	assert.Equal(t, "south-ak", ak)
+	assert.Equal(t, "cn-south-1", project)
+	ak, project = signed("https://cse.example.com/east/v1/kie")
+	assert.Equal(t, "east-ak", ak)
+	assert.Equal(t, "cn-east-2", project)
+	ak, project = signed("https://cse.example.com/west/v1/kie")
+	assert.Equal(t, "pa1", ak, "no rule matches, use default credential")
+	assert.Equal(t, "pp1", project)
+
+	sign, err := auth.GetProfileSignFunc("east")
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("GET", "http://127.0.0.1", nil)
+	assert.NoError(t, sign(r))
+	assert.Equal(t, "east-ak", r.Header.Get(auth.HeaderServiceAk))
+	_, err = auth.GetProfileSignFunc("west")
+	assert.True(t, errors.Is(err, auth.ErrProfileNotExist))
+
+	t.Run("profile without project works in the project of service", func(t *testing.T) {
+		assert.NoError(t, archaius.Delete("servicecomb.credentials.profiles.east.project"))
+		assert.NoError(t, auth.LoadAuth())
+		ak, project := signed("https://cse.cn-south-1.example.com:30100/v4/default/registry/microservices")
+		assert.Equal(t, "south-ak", ak)
+		assert.Equal(t, "cn-south-1", project)
+		ak, project = signed("https://cse.example.com/east/v1/kie")
+		assert.Equal(t, "east-ak", ak)
+		l, err := auth.ResolveLocation("")
+		assert.NoError(t, err)
+		assert.Equal(t, l.Project, project)
+	})
+
+	t.Log("rule of unknown profile")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.endpoints", []interface{}{
+		map[string]interface{}{"pattern": "*.example.com", "profile": "west"},
+	}))
+	_, err = auth.LoadEndpointSigner()
+	assert.True(t, errors.Is(err, auth.ErrProfileNotExist))
+}
@@ -0,0 +1,197 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/openlog"
+)
+
+const (
+	// HeaderProjectID carries project id in requests signed by APIG signer
+	HeaderProjectID = "X-Project-Id"
+	// DefaultIAMEndpoint is used to look up project id, if servicecomb.credentials.iam.endpoint is not set and region is unknown
+	DefaultIAMEndpoint = "https://iam.myhuaweicloud.com"
+
+	keyResolveProjectID = "servicecomb.credentials.resolveProjectID"
+	keyProjectIDCache   = "servicecomb.credentials.projectIDCache"
+)
+
+var (
+	projectIDs sync.Map
+	// projectIDLock makes concurrent callers of a missed id wait for one lookup
+	projectIDLock sync.Mutex
+	// projectIDFileLock guards read-modify-write of the cache file
+	projectIDFileLock sync.Mutex
+)
+
+// ProjectID returns id of a project name, it is looked up from IAM /v3/projects with the loaded credential.
+// ids are cached in memory, and in file servicecomb.credentials.projectIDCache,
+// so that a restarted service does not look up again
+func ProjectID(name string) (string, error) {
+	a, err := loadedAkskAuth()
+	if err != nil {
+		return "", err
+	}
+	if a.projectID != "" && a.project == name {
+		return a.projectID, nil
+	}
+	return a.lookupProject(name)
+}
+
+// CurrentProject returns project name and id of current credential
+func CurrentProject() (name, id string, err error) {
+	a, err := loadedAkskAuth()
+	if err != nil {
+		return "", "", err
+	}
+	if a.projectID != "" {
+		return a.project, a.projectID, nil
+	}
+	id, err = a.lookupProject(a.project)
+	if err != nil {
+		return a.project, "", err
+	}
+	return a.project, id, nil
+}
+
+// resolveProjectID fills project id of credential if servicecomb.credentials.resolveProjectID is true,
+// the credential still works without id if it can not be looked up
+func resolveProjectID(c *Credential, sk string) {
+	if c.ProjectID != "" || !archaius.GetBool(keyResolveProjectID, false) {
+		return
+	}
+	id, err := lookupProjectID(c, sk, c.Project)
+	if err != nil {
+		openlog.Warn(fmt.Sprintf("look up id of project [%s] failed: %s", c.Project, Redact(err.Error())))
+		return
+	}
+	c.ProjectID = id
+}
+
+// lookupProjectID reads cache in memory and file, then asks IAM.
+// project names are unique in an account only, so cache is keyed by ak as well
+func lookupProjectID(c *Credential, sk, name string) (string, error) {
+	key := projectIDCacheKey(c.AccessKey, name)
+	if id, ok := projectIDs.Load(key); ok {
+		return id.(string), nil
+	}
+	projectIDLock.Lock()
+	defer projectIDLock.Unlock()
+	if id, ok := projectIDs.Load(key); ok {
+		return id.(string), nil
+	}
+	if id := readProjectIDFile()[key]; id != "" {
+		projectIDs.Store(key, id)
+		return id, nil
+	}
+	sign, err := GetAPIGSignFunc(c.AccessKey, sk, c.SecurityToken)
+	if err != nil {
+		return "", err
+	}
+	client, err := newIAMClient(iamEndpoint(name), sign)
+	if err != nil {
+		return "", err
+	}
+	id, err := client.GetProjectID(name)
+	if err != nil {
+		return "", err
+	}
+	projectIDs.Store(key, id)
+	if err := writeProjectIDFile(key, id); err != nil {
+		openlog.Warn(fmt.Sprintf("can not cache project id: %s", err))
+	}
+	openlog.Info(fmt.Sprintf("id of project [%s] is %s", name, id))
+	return id, nil
+}
+
+// projectIDOf returns id of the project of request context, or id of the credential.
+// id of a project in context is looked up only if servicecomb.credentials.resolveProjectID is true
+func projectIDOf(r *http.Request, c *Credential, sk string) (string, error) {
+	p, ok := ProjectFromContext(r.Context())
+	if !ok || p == c.Project {
+		return c.ProjectID, nil
+	}
+	if !archaius.GetBool(keyResolveProjectID, false) {
+		return "", nil
+	}
+	return lookupProjectID(c, sk, p)
+}
+
+func projectIDCacheKey(ak, name string) string {
+	sum := sha256.Sum256([]byte(ak))
+	return hex.EncodeToString(sum[:8]) + "/" + name
+}
+
+// iamEndpoint returns the IAM endpoint of the region project belongs to
+func iamEndpoint(project string) string {
+	if v := archaius.GetString(keyIAMEndpoint, ""); v != "" {
+		return v
+	}
+	if region := regionOfProject(project); region != "" {
+		return "https://iam." + region + ".myhuaweicloud.com"
+	}
+	return DefaultIAMEndpoint
+}
+
+func projectIDCacheFile() string {
+	return archaius.GetString(keyProjectIDCache, filepath.Join(os.TempDir(), "go-chassis-cloud", "project_id.json"))
+}
+
+func readProjectIDFile() map[string]string {
+	ids := make(map[string]string)
+	b, err := ioutil.ReadFile(projectIDCacheFile())
+	if err != nil {
+		return ids
+	}
+	if err := json.Unmarshal(b, &ids); err != nil {
+		openlog.Warn(fmt.Sprintf("ignore invalid project id cache: %s", err))
+	}
+	return ids
+}
+
+// writeProjectIDFile replaces the cache file, so that a reader never sees a partial file
+func writeProjectIDFile(key, id string) error {
+	projectIDFileLock.Lock()
+	defer projectIDFileLock.Unlock()
+	ids := readProjectIDFile()
+	ids[key] = id
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	name := projectIDCacheFile()
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
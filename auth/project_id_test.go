@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectID(t *testing.T) {
+	var lookups int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/projects", r.URL.Path)
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&lookups, 1)
+		if name := r.URL.Query().Get("name"); name == "cn-north-4_sub" {
+			w.Write([]byte(`{"projects":[{"id":"pid-` + name + `","name":"` + name + `"}]}`))
+			return
+		}
+		w.Write([]byte(`{"projects":[]}`))
+	}))
+	defer s.Close()
+
+	credentialFilePath := testInitEnv(t)
+	auth.ResetProjectIDs()
+	testWriteFile(t, credentialFilePath, "pidak1", "pidsk1", "cn-north-4_sub", "")
+	cacheFile := filepath.Join(t.TempDir(), "project_id.json")
+	for k, v := range map[string]interface{}{
+		"servicecomb.credentials.iam.endpoint":     s.URL,
+		"servicecomb.credentials.projectIDCache":   cacheFile,
+		"servicecomb.credentials.resolveProjectID": true,
+		"servicecomb.credentials.signer":           auth.SignerAPIG,
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+	assert.NoError(t, auth.LoadAkskAuth())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+
+	ctx, info := auth.WithSignInfo(nil)
+	r, _ := http.NewRequest(http.MethodGet, "https://cse.cn-north-4.myhuaweicloud.com", nil)
+	r = r.WithContext(ctx)
+	assert.NoError(t, httpclient.SignRequest(r))
+	assert.Equal(t, "pid-cn-north-4_sub", r.Header.Get(auth.HeaderProjectID))
+	assert.Equal(t, "cn-north-4_sub", info.Project)
+	assert.Equal(t, "pid-cn-north-4_sub", info.ProjectID)
+
+	name, id, err := auth.CurrentProject()
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-north-4_sub", name)
+	assert.Equal(t, "pid-cn-north-4_sub", id)
+	id, err = auth.ProjectID("cn-north-4_sub")
+	assert.NoError(t, err)
+	assert.Equal(t, "pid-cn-north-4_sub", id)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups), "id is cached")
+	b, err := ioutil.ReadFile(cacheFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "pid-cn-north-4_sub")
+	assert.NotContains(t, string(b), "pidak1")
+
+	_, err = auth.ProjectID("cn-north-1")
+	assert.True(t, errors.Is(err, iam.ErrProjectNotFound))
+}
@@ -0,0 +1,233 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+)
+
+// build-in credential provider names
+const (
+	ProviderFile     = "file"
+	ProviderArchaius = "archaius"
+
+	keyProviders = "servicecomb.credentials.providers"
+)
+
+// DefaultProviderChain is the chain used when servicecomb.credentials.providers is not set.
+// credentials configured for the service, in files, secrets, CSMS or config, come before env,
+// because env usually carries the bootstrap credential of KMS or CSMS,
+// which must not shadow the service credential they decrypt or fetch
+var DefaultProviderChain = []string{ProviderFile, ProviderSecretDir, ProviderCSMS, ProviderArchaius, ProviderEnv, ProviderMetadata}
+
+// serviceCipherProviders are providers whose credential has no cipher setting of its own,
+// their sk is decrypted by servicecomb.credentials.akskCustomCipher when they provide credential of the service
+var serviceCipherProviders = map[string]bool{ProviderEnv: true, ProviderSecretDir: true}
+
+// Credential is an ak/sk credential, a temporary credential also has security token and expire time
+type Credential struct {
+	AccessKey        string    `yaml:"accessKey"`
+	SecretKey        string    `yaml:"secretKey"`
+	AkskCustomCipher string    `yaml:"akskCustomCipher"`
+	Project          string    `yaml:"project"`
+	ProjectID        string    `yaml:"projectID"`
+	SecurityToken    string    `yaml:"securityToken"`
+	ExpiresAt        time.Time `yaml:"expiresAt"`
+	// Secondary is another ak sk valid during rotation, it is used if this one is rejected
+	Secondary *Credential `yaml:"secondary"`
+}
+
+// Temporary returns true if this credential expires
+func (c *Credential) Temporary() bool {
+	return !c.ExpiresAt.IsZero()
+}
+
+// PlainSecretKey decrypts sk with akskCustomCipher
+func (c *Credential) PlainSecretKey() (string, error) {
+	return decryptSecretKey(scopeCredential, c)
+}
+
+// CredentialProvider is a source of ak/sk credential
+type CredentialProvider interface {
+	// Name is the name used in servicecomb.credentials.providers
+	Name() string
+	// Retrieve returns ErrAuthConfNotExist if this source has no credential,
+	// then the next provider in the chain will be tried.
+	// a provider of temporary credential should return a new one when it is called again before expiry
+	Retrieve() (*Credential, error)
+}
+
+var (
+	providers     = make(map[string]CredentialProvider)
+	providersLock sync.RWMutex
+)
+
+// InstallCredentialProvider registers a provider, a provider with the same name will be replaced
+func InstallCredentialProvider(p CredentialProvider) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	providers[p.Name()] = p
+}
+
+// GetCredentialProvider returns a registered provider
+func GetCredentialProvider(name string) (CredentialProvider, error) {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown credential provider [%s]", name)
+	}
+	return p, nil
+}
+
+// ProviderChain returns provider names in the order they will be tried,
+// it can be customized with servicecomb.credentials.providers, for example "env,file,archaius"
+func ProviderChain() []string {
+	v := archaius.GetString(keyProviders, "")
+	if v == "" {
+		return DefaultProviderChain
+	}
+	return splitProviders(v)
+}
+
+func splitProviders(v string) []string {
+	chain := make([]string, 0)
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// providerTrace is told the result of each provider walked, err is nil if the provider has credential
+type providerTrace func(provider string, err error)
+
+func (t providerTrace) record(provider string, err error) {
+	if t != nil {
+		t(provider, err)
+	}
+}
+
+// retrieveCredential walks the provider chain, and returns the credential of first provider who has one
+func retrieveCredential(trace providerTrace) (*Credential, string, error) {
+	c, provider, err := walkProviders(ProviderChain(), trace)
+	if err != nil {
+		return nil, provider, err
+	}
+	if c.AkskCustomCipher == "" && serviceCipherProviders[provider] {
+		c.AkskCustomCipher = serviceCipher()
+	}
+	return c, provider, nil
+}
+
+// serviceCipher is the cipher of service credential, it falls back to the legacy cse.credentials.akskCustomCipher
+func serviceCipher() string {
+	return archaius.GetString(common.AKSKCustomCipher, archaius.GetString(keyCipher, ""))
+}
+
+// RetrieveCredential walks the given providers, and returns the credential of first provider who has one,
+// it helps a component to get its own bootstrap credential from providers it trusts.
+// the credential keeps the cipher setting of its provider, it never inherits the cipher of service credential
+func RetrieveCredential(chain ...string) (*Credential, string, error) {
+	return walkProviders(chain, nil)
+}
+
+func walkProviders(chain []string, trace providerTrace) (*Credential, string, error) {
+	for _, name := range chain {
+		p, err := GetCredentialProvider(name)
+		if err != nil {
+			trace.record(name, err)
+			return nil, "", err
+		}
+		c, err := p.Retrieve()
+		trace.record(name, err)
+		if err == ErrAuthConfNotExist {
+			continue
+		}
+		if err != nil {
+			return nil, name, fmt.Errorf("credential provider [%s] failed: %w", name, err)
+		}
+		return c, name, nil
+	}
+	return nil, "", ErrAuthConfNotExist
+}
+
+// fileProvider reads ${CIPHER_ROOT}/certificate.yaml,
+// once the file exists, it takes precedence even if the credential in it is empty
+type fileProvider struct{}
+
+func (p *fileProvider) Name() string {
+	return ProviderFile
+}
+
+func (p *fileProvider) Retrieve() (*Credential, error) {
+	v, exist := os.LookupEnv(CipherRootEnv)
+	if !exist {
+		return nil, ErrAuthConfNotExist
+	}
+	akskFile := filepath.Join(v, KeytoolAkskFile)
+	yamlContent, err := ioutil.ReadFile(akskFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrAuthConfNotExist
+		}
+		return nil, err
+	}
+	return unmarshalCredential(yamlContent)
+}
+
+// archaiusProvider reads servicecomb.credentials, falls back to cse.credentials
+type archaiusProvider struct{}
+
+func (p *archaiusProvider) Name() string {
+	return ProviderArchaius
+}
+
+func (p *archaiusProvider) Retrieve() (*Credential, error) {
+	c := &Credential{}
+	c.AccessKey = archaius.GetString(keyAKV2, archaius.GetString(keyAK, ""))
+	c.SecretKey = archaius.GetString(keySKV2, archaius.GetString(keySK, ""))
+	c.Project = archaius.GetString(keyProjectV2, archaius.GetString(keyProject, ""))
+	c.AkskCustomCipher = serviceCipher()
+	if c.AccessKey == "" && c.SecretKey == "" {
+		return nil, ErrAuthConfNotExist
+	}
+	if ak := archaius.GetString(keySecondaryAK, ""); ak != "" {
+		c.Secondary = &Credential{
+			AccessKey:        ak,
+			SecretKey:        archaius.GetString(keySecondarySK, ""),
+			AkskCustomCipher: archaius.GetString(keySecondaryCipher, ""),
+		}
+	}
+	return c, nil
+}
+
+func init() {
+	InstallCredentialProvider(&fileProvider{})
+	InstallCredentialProvider(&archaiusProvider{})
+}
@@ -0,0 +1,193 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/csms"
+	"github.com/go-chassis/openlog"
+	"gopkg.in/yaml.v2"
+)
+
+// ProviderCSMS reads credential from a secret of cloud secret management service
+const ProviderCSMS = "csms"
+
+// DefaultCSMSRefreshInterval is how often to check if a new version of the secret is published
+const DefaultCSMSRefreshInterval = 5 * time.Minute
+
+const (
+	keyCSMS                = "servicecomb.credentials.csms"
+	keyCSMSEndpoint        = "servicecomb.credentials.csms.endpoint"
+	keyCSMSProjectID       = "servicecomb.credentials.csms.projectID"
+	keyCSMSSecret          = "servicecomb.credentials.csms.secret"
+	keyCSMSVersion         = "servicecomb.credentials.csms.version"
+	keyCSMSProviders       = "servicecomb.credentials.csms.providers"
+	keyCSMSRefreshInterval = "servicecomb.credentials.csms.refreshInterval"
+)
+
+// DefaultBootstrapProviders provide the credential to fetch or decrypt the credential of service,
+// it comes from env or instance metadata, so that it needs no secret in config
+var DefaultBootstrapProviders = []string{ProviderEnv, ProviderMetadata}
+
+// csmsProvider reads servicecomb.credentials.csms.secret, the secret string is like
+// {"accessKey": "ak", "secretKey": "sk", "project": "cn-north-1"}.
+// if version is latest, it checks the secret periodically, and reloads credential once a new version is published
+type csmsProvider struct {
+	mu      sync.Mutex
+	version string
+	timer   *time.Timer
+}
+
+func (p *csmsProvider) Name() string {
+	return ProviderCSMS
+}
+
+func (p *csmsProvider) Retrieve() (*Credential, error) {
+	name := archaius.GetString(keyCSMSSecret, "")
+	if name == "" {
+		return nil, ErrAuthConfNotExist
+	}
+	client, err := newCSMSClient()
+	if err != nil {
+		return nil, err
+	}
+	version := archaius.GetString(keyCSMSVersion, csms.VersionLatest)
+	v, err := client.GetSecretVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	c := &Credential{}
+	// json is yaml as well
+	if err := yaml.Unmarshal([]byte(v.SecretString), c); err != nil {
+		return nil, fmt.Errorf("invalid secret [%s] version [%s]: %v", name, v.ID, err)
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, fmt.Errorf("secret [%s] version [%s] has no ak or sk", name, v.ID)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.version = v.ID
+	if version == csms.VersionLatest {
+		p.schedulePollLocked()
+	}
+	return c, nil
+}
+
+func (p *csmsProvider) schedulePollLocked() {
+	if p.timer != nil {
+		return
+	}
+	p.timer = time.AfterFunc(csmsRefreshInterval(), p.poll)
+}
+
+// poll reloads credential if the latest version of secret changes,
+// it stops once the secret is not configured or a version is pinned
+func (p *csmsProvider) poll() {
+	p.mu.Lock()
+	p.timer = nil
+	current := p.version
+	p.mu.Unlock()
+	name := archaius.GetString(keyCSMSSecret, "")
+	if name == "" || archaius.GetString(keyCSMSVersion, csms.VersionLatest) != csms.VersionLatest {
+		return
+	}
+	v, err := getLatestSecretVersion(name)
+	if err != nil {
+		emitCredentialEvent(CredentialEvent{
+			Type:           EventRefreshFailed,
+			CredentialType: TypeAKSK,
+			Provider:       ProviderCSMS,
+			Error:          fmt.Sprintf("check csms secret [%s] failed: %s", name, err),
+		})
+	} else if v.ID != current {
+		openlog.Info(fmt.Sprintf("csms secret [%s] version changed from [%s] to [%s]", name, current, v.ID))
+		reloadAkskAuth()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.schedulePollLocked()
+}
+
+func getLatestSecretVersion(name string) (*csms.SecretVersion, error) {
+	client, err := newCSMSClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSecretVersion(name, csms.VersionLatest)
+}
+
+func csmsRefreshInterval() time.Duration {
+	d, err := time.ParseDuration(archaius.GetString(keyCSMSRefreshInterval, ""))
+	if err != nil || d <= 0 {
+		return DefaultCSMSRefreshInterval
+	}
+	return d
+}
+
+// newCSMSClient signs requests with bootstrap credential of servicecomb.credentials.csms.providers
+func newCSMSClient() (*csms.Client, error) {
+	endpoint := archaius.GetString(keyCSMSEndpoint, "")
+	if endpoint == "" {
+		return nil, errors.New(keyCSMSEndpoint + " is empty")
+	}
+	sign, err := GetBootstrapSignFunc(bootstrapProviders(keyCSMSProviders)...)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return csms.New(csms.Options{
+		Endpoint:  endpoint,
+		ProjectID: archaius.GetString(keyCSMSProjectID, ""),
+		Signer:    sign,
+		TLSConfig: tlsConfig,
+	})
+}
+
+// bootstrapProviders reads a comma separated provider list, DefaultBootstrapProviders is used if it is empty
+func bootstrapProviders(key string) []string {
+	v := archaius.GetString(key, "")
+	if v == "" {
+		return DefaultBootstrapProviders
+	}
+	return splitProviders(v)
+}
+
+// GetBootstrapSignFunc signs with SDK-HMAC-SHA256 by the credential of given providers
+func GetBootstrapSignFunc(chain ...string) (SignRequest, error) {
+	c, provider, err := RetrieveCredential(chain...)
+	if err != nil {
+		return nil, fmt.Errorf("no bootstrap credential: %w", err)
+	}
+	sk, err := decryptSecretKey(scopeBootstrap, c)
+	if err != nil {
+		return nil, fmt.Errorf("bootstrap credential of provider [%s]: %w", provider, err)
+	}
+	return GetAPIGSignFunc(c.AccessKey, sk, c.SecurityToken)
+}
+
+func init() {
+	InstallCredentialProvider(&csmsProvider{})
+}
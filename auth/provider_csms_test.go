@@ -0,0 +1,61 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSMSProvider(t *testing.T) {
+	testInitEnv(t)
+	var mu sync.Mutex
+	version := 1
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), auth.APIGAlgorithm+" Access=bootstrap-ak,") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/v1/pid/secrets/cse-credential/versions/latest", r.URL.Path)
+		mu.Lock()
+		defer mu.Unlock()
+		secret := fmt.Sprintf(`{\"accessKey\":\"sa%d\",\"secretKey\":\"ss%d\",\"project\":\"sp\"}`, version, version)
+		fmt.Fprintf(w, `{"version":{"version_metadata":{"id":"v%d","secret_name":"cse-credential"},"secret_string":"%s"}}`, version, secret)
+	}))
+	defer s.Close()
+	os.Setenv(auth.EnvAccessKey, "bootstrap-ak")
+	os.Setenv(auth.EnvSecretKey, "bootstrap-sk")
+	defer os.Unsetenv(auth.EnvAccessKey)
+	defer os.Unsetenv(auth.EnvSecretKey)
+	for k, v := range map[string]string{
+		"servicecomb.credentials.providers":            "csms",
+		"servicecomb.credentials.csms.endpoint":        s.URL,
+		"servicecomb.credentials.csms.projectID":       "pid",
+		"servicecomb.credentials.csms.refreshInterval": "100ms",
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+	// delete secret first to stop polling
+	assert.NoError(t, archaius.Set("servicecomb.credentials.csms.secret", "cse-credential"))
+	defer archaius.Delete("servicecomb.credentials.csms.secret")
+
+	assert.NoError(t, auth.LoadAuth())
+	testCheckAkAndProject(t, "sa1", "sp")
+
+	t.Log("publish a new version")
+	mu.Lock()
+	version = 2
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return testSignedAk(t) == "sa2"
+	}, 3*time.Second, 50*time.Millisecond)
+}
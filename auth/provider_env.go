@@ -0,0 +1,62 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"os"
+)
+
+// ProviderEnv reads credential from environment variables, which is common in CI and local development
+const ProviderEnv = "env"
+
+// environment variables of credential, same as huawei cloud SDK
+const (
+	EnvAccessKey     = "HUAWEICLOUD_SDK_AK"
+	EnvSecretKey     = "HUAWEICLOUD_SDK_SK"
+	EnvProjectID     = "HUAWEICLOUD_SDK_PROJECT_ID"
+	EnvSecurityToken = "HUAWEICLOUD_SDK_SECURITY_TOKEN"
+	// EnvAkskCustomCipher is the cipher to decrypt HUAWEICLOUD_SDK_SK. if it is not set,
+	// the service credential is decrypted by servicecomb.credentials.akskCustomCipher,
+	// while a bootstrap credential is plain text
+	EnvAkskCustomCipher = "HUAWEICLOUD_SDK_AKSK_CIPHER"
+)
+
+// envProvider reads HUAWEICLOUD_SDK_* environment variables
+type envProvider struct{}
+
+func (p *envProvider) Name() string {
+	return ProviderEnv
+}
+
+func (p *envProvider) Retrieve() (*Credential, error) {
+	c := &Credential{
+		AccessKey:     os.Getenv(EnvAccessKey),
+		SecretKey:     os.Getenv(EnvSecretKey),
+		ProjectID:     os.Getenv(EnvProjectID),
+		SecurityToken: os.Getenv(EnvSecurityToken),
+	}
+	if c.AccessKey == "" && c.SecretKey == "" {
+		return nil, ErrAuthConfNotExist
+	}
+	c.AkskCustomCipher = os.Getenv(EnvAkskCustomCipher)
+	return c, nil
+}
+
+func init() {
+	InstallCredentialProvider(&envProvider{})
+}
@@ -0,0 +1,98 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/metadata"
+	"github.com/go-chassis/openlog"
+)
+
+// ProviderMetadata fetches temporary credential of the agency bound to ECS/CCE instance
+const ProviderMetadata = "metadata"
+
+const keyMetadataEndpoint = "servicecomb.credentials.metadata.endpoint"
+
+// metadataProvider caches the credential from instance metadata service,
+// and fetches a new one when it is going to expire
+type metadataProvider struct {
+	mu     sync.Mutex
+	cached *Credential
+}
+
+func (p *metadataProvider) Name() string {
+	return ProviderMetadata
+}
+
+func (p *metadataProvider) Retrieve() (*Credential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cached != nil && time.Until(p.cached.ExpiresAt) > refreshAhead() {
+		c := *p.cached
+		return &c, nil
+	}
+	endpoint := archaius.GetString(keyMetadataEndpoint, metadata.DefaultEndpoint)
+	client, err := metadata.New(metadata.Options{Endpoint: endpoint})
+	if err != nil {
+		return nil, err
+	}
+	sk, err := client.GetSecurityKey()
+	if err != nil && p.cached != nil && time.Now().Before(p.cached.ExpiresAt) {
+		emitCredentialEvent(CredentialEvent{
+			Type:           EventRefreshFailed,
+			CredentialType: TypeAKSK,
+			Provider:       ProviderMetadata,
+			AccessKey:      MaskSecret(p.cached.AccessKey),
+			Error:          "use the cached credential: " + err.Error(),
+		})
+		c := *p.cached
+		return &c, nil
+	}
+	if err != nil {
+		var urlErr *url.Error
+		// no valid credential is cached, an instance without agency, or without metadata service of huawei cloud
+		// has no credential, the next provider is tried
+		if err == metadata.ErrNoSecurityKey || errors.Is(err, metadata.ErrUnexpectedStatus) || errors.As(err, &urlErr) {
+			openlog.Debug(fmt.Sprintf("no credential from instance metadata [%s]: %s", endpoint, Redact(err.Error())))
+			return nil, ErrAuthConfNotExist
+		}
+		return nil, err
+	}
+	expiresAt, err := time.Parse(time.RFC3339, sk.Credential.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expires_at [%s]: %v", sk.Credential.ExpiresAt, err)
+	}
+	p.cached = &Credential{
+		AccessKey:     sk.Credential.Access,
+		SecretKey:     sk.Credential.Secret,
+		SecurityToken: sk.Credential.SecurityToken,
+		ExpiresAt:     expiresAt,
+	}
+	c := *p.cached
+	return &c, nil
+}
+
+func init() {
+	InstallCredentialProvider(&metadataProvider{})
+}
@@ -0,0 +1,129 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chassis/go-archaius"
+)
+
+// ProviderSecretDir reads a secret directory, like a kubernetes secret volume
+const ProviderSecretDir = "secretDir"
+
+// file names in secret directory, project is optional
+const (
+	SecretFileAK      = "ak"
+	SecretFileSK      = "sk"
+	SecretFileProject = "project"
+
+	// k8sDataLink is the symlink kubelet swaps to update all files of a secret volume at once
+	k8sDataLink = "..data"
+
+	keySecretDir = "servicecomb.credentials.secretDir"
+
+	secretDirReadRetries = 3
+)
+
+var errSecretDirChanging = errors.New("secret directory keeps changing")
+
+// secretDir is servicecomb.credentials.secretDir, it is ${CIPHER_ROOT} by default
+func secretDir() string {
+	return archaius.GetString(keySecretDir, os.Getenv(CipherRootEnv))
+}
+
+// secretDirProvider reads ak, sk and project files in secret directory.
+// if the directory is a kubernetes secret volume, all files are read from the same version,
+// so that a rotation never produces a half-updated ak sk pair
+type secretDirProvider struct{}
+
+func (p *secretDirProvider) Name() string {
+	return ProviderSecretDir
+}
+
+func (p *secretDirProvider) Retrieve() (*Credential, error) {
+	dir := secretDir()
+	if dir == "" {
+		return nil, ErrAuthConfNotExist
+	}
+	for i := 0; i < secretDirReadRetries; i++ {
+		version, _ := os.Readlink(filepath.Join(dir, k8sDataLink))
+		c, err := readSecretDir(dir, version)
+		// kubelet swapped ..data during reading, the files may come from different versions
+		if v, _ := os.Readlink(filepath.Join(dir, k8sDataLink)); v != version {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, errSecretDirChanging
+}
+
+// readSecretDir reads files of a version of secret volume, or the directory itself if version is empty
+func readSecretDir(dir, version string) (*Credential, error) {
+	if version != "" {
+		if filepath.IsAbs(version) {
+			dir = version
+		} else {
+			dir = filepath.Join(dir, version)
+		}
+	}
+	read := func(name string) (string, error) {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	ak, akErr := read(SecretFileAK)
+	sk, skErr := read(SecretFileSK)
+	if os.IsNotExist(akErr) && os.IsNotExist(skErr) {
+		return nil, ErrAuthConfNotExist
+	}
+	if akErr != nil {
+		return nil, fmt.Errorf("incomplete secret in %s: %w", dir, akErr)
+	}
+	if skErr != nil {
+		return nil, fmt.Errorf("incomplete secret in %s: %w", dir, skErr)
+	}
+	project, err := read(SecretFileProject)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &Credential{AccessKey: ak, SecretKey: sk, Project: project}, nil
+}
+
+// isSecretDirFile returns true if a change of the file may change the credential in secret directory
+func isSecretDirFile(name string) bool {
+	switch filepath.Base(name) {
+	case k8sDataLink, SecretFileAK, SecretFileSK, SecretFileProject:
+		return true
+	}
+	return false
+}
+
+func init() {
+	InstallCredentialProvider(&secretDirProvider{})
+}
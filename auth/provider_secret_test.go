@@ -0,0 +1,60 @@
+package auth_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// testWriteSecretVersion writes a version of kubernetes secret volume, and swaps ..data to it like kubelet does
+func testWriteSecretVersion(t *testing.T, dir, version string, files map[string]string) {
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, version), 0700))
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, version, name), []byte(content+"\n"), 0600))
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			assert.NoError(t, os.Symlink(filepath.Join("..data", name), link))
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	assert.NoError(t, os.Symlink(version, tmp))
+	assert.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+}
+
+func TestSecretDirProvider(t *testing.T) {
+	cipherRoot := filepath.Dir(testInitEnv(t))
+	for _, name := range []string{auth.SecretFileAK, auth.SecretFileSK, auth.SecretFileProject, "..data", "..v1", "..v2", "..v3"} {
+		defer os.RemoveAll(filepath.Join(cipherRoot, name))
+	}
+	testWriteSecretVersion(t, cipherRoot, "..v1", map[string]string{
+		auth.SecretFileAK:      "ka1",
+		auth.SecretFileSK:      "ks1",
+		auth.SecretFileProject: "kp1",
+	})
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", auth.ProviderSecretDir))
+	defer archaius.Delete("servicecomb.credentials.providers")
+	assert.NoError(t, auth.LoadAuth())
+	testCheckAkAndProject(t, "ka1", "kp1")
+
+	t.Log("keep old credential if the new version is incomplete")
+	testWriteSecretVersion(t, cipherRoot, "..v2", map[string]string{auth.SecretFileAK: "ka2"})
+	time.Sleep(500 * time.Millisecond)
+	testCheckAkAndProject(t, "ka1", "kp1")
+
+	t.Log("reload after ..data swapped to a complete version")
+	testWriteSecretVersion(t, cipherRoot, "..v3", map[string]string{
+		auth.SecretFileAK:      "ka3",
+		auth.SecretFileSK:      "ks3",
+		auth.SecretFileProject: "kp3",
+	})
+	assert.Eventually(t, func() bool {
+		return testSignedAk(t) == "ka3"
+	}, 3*time.Second, 50*time.Millisecond)
+	testCheckAkAndProject(t, "ka3", "kp3")
+}
@@ -0,0 +1,221 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/cari/security"
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/security/cipher"
+	"github.com/stretchr/testify/assert"
+)
+
+type testProvider struct {
+	mu sync.Mutex
+	c  *auth.Credential
+}
+
+func (p *testProvider) set(c *auth.Credential) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.c = c
+}
+
+func (p *testProvider) Name() string {
+	return "custom"
+}
+
+func (p *testProvider) Retrieve() (*auth.Credential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.c == nil {
+		return nil, auth.ErrAuthConfNotExist
+	}
+	c := *p.c
+	return &c, nil
+}
+
+func TestProviderChain(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "fa", "fs", "fp", "")
+	assert.Equal(t, auth.DefaultProviderChain, auth.ProviderChain())
+
+	p := &testProvider{c: &auth.Credential{AccessKey: "ca", SecretKey: "cs", Project: "cp"}}
+	auth.InstallCredentialProvider(p)
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "custom, file"))
+	defer archaius.Delete("servicecomb.credentials.providers")
+	assert.Equal(t, []string{"custom", "file"}, auth.ProviderChain())
+
+	t.Run("custom provider wins", func(t *testing.T) {
+		assert.NoError(t, auth.LoadAkskAuth())
+		testCheckAkAndProject(t, "ca", "cp")
+	})
+	t.Run("fall back to next provider", func(t *testing.T) {
+		p.set(nil)
+		assert.NoError(t, auth.LoadAkskAuth())
+		// a reload triggered by config change may be still running
+		assert.Eventually(t, func() bool {
+			return testSignedAk(t) == "fa"
+		}, 3*time.Second, 50*time.Millisecond)
+		testCheckAkAndProject(t, "fa", "fp")
+	})
+	t.Run("unknown provider", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "unknown,file"))
+		assert.Error(t, auth.LoadAkskAuth())
+	})
+}
+
+func TestMetadataProvider(t *testing.T) {
+	testInitEnv(t)
+	auth.ResetMetadataProvider()
+	defer auth.ResetMetadataProvider()
+	config.GlobalDefinition.ServiceComb.Registry.Address = "https://cse.cn-north-1.myhwclouds.com:443"
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		w.Write([]byte(`{"credential":{"access":"ma","secret":"ms","securitytoken":"mt","expires_at":"` + expiresAt + `"}}`))
+	}))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "metadata"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.metadata.endpoint", s.URL))
+	defer archaius.Delete("servicecomb.credentials.providers")
+	defer archaius.Delete("servicecomb.credentials.metadata.endpoint")
+
+	t.Run("fetch and cache temporary credential", func(t *testing.T) {
+		assert.NoError(t, auth.LoadAkskAuth())
+		assert.NoError(t, auth.LoadAkskAuth())
+		assert.Equal(t, 1, calls)
+		req, _ := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+		assert.NoError(t, httpclient.SignRequest(req))
+		assert.Equal(t, "ma", req.Header.Get(auth.HeaderServiceAk))
+		assert.Equal(t, "mt", req.Header.Get(auth.HeaderSecurityToken))
+	})
+	t.Run("metadata service not reachable", func(t *testing.T) {
+		s.Close()
+		p, err := auth.GetCredentialProvider(auth.ProviderMetadata)
+		assert.NoError(t, err)
+		_, err = p.Retrieve()
+		assert.NoError(t, err, "cached credential is still valid")
+		assert.NoError(t, archaius.Set("servicecomb.credentials.metadata.endpoint", "http://127.0.0.1:1"))
+		assert.NoError(t, auth.LoadAkskAuth())
+	})
+	for _, status := range []int{http.StatusUnauthorized, http.StatusServiceUnavailable} {
+		t.Run(fmt.Sprintf("metadata service responds %d", status), func(t *testing.T) {
+			auth.ResetMetadataProvider()
+			other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}))
+			defer other.Close()
+			assert.NoError(t, archaius.Set("servicecomb.credentials.metadata.endpoint", other.URL))
+			_, _, err := auth.RetrieveCredential(auth.ProviderMetadata)
+			assert.Equal(t, auth.ErrAuthConfNotExist, err)
+
+			credentialFilePath := testInitEnv(t)
+			testWriteFile(t, credentialFilePath, "mfa", "mfs", "mfp", "")
+			assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "metadata,file"))
+			assert.NoError(t, auth.LoadAkskAuth(), "next provider is tried")
+			testCheckAkAndProject(t, "mfa", "mfp")
+		})
+	}
+}
+
+type testReverseCipher struct{}
+
+func (c *testReverseCipher) Encrypt(src string) (string, error) {
+	return testReverse(src), nil
+}
+
+func (c *testReverseCipher) Decrypt(src string) (string, error) {
+	return testReverse(src), nil
+}
+
+func testReverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func TestEnvProvider(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "fa", "fs", "fp", "")
+	cipher.InstallCipherPlugin("reverse", func() security.Cipher { return &testReverseCipher{} })
+	for k, v := range map[string]string{
+		auth.EnvAccessKey:        "ea",
+		auth.EnvSecretKey:        testReverse("es"),
+		auth.EnvProjectID:        "ep",
+		auth.EnvAkskCustomCipher: "reverse",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	t.Run("file takes precedence over env", func(t *testing.T) {
+		assert.NoError(t, auth.LoadAkskAuth())
+		testCheckAkAndProject(t, "fa", "fp")
+	})
+	assert.NoError(t, os.Remove(credentialFilePath))
+	t.Run("config takes precedence over env", func(t *testing.T) {
+		os.Unsetenv(auth.EnvAkskCustomCipher)
+		defer os.Setenv(auth.EnvAkskCustomCipher, "reverse")
+		for k, v := range map[string]string{
+			"servicecomb.credentials.accessKey": "svc-ak",
+			"servicecomb.credentials.secretKey": testReverse("svc-sk"),
+			common.AKSKCustomCipher:             "reverse",
+		} {
+			assert.NoError(t, archaius.Set(k, v))
+			defer archaius.Delete(k)
+		}
+		assert.NoError(t, auth.LoadAkskAuth())
+		testCheckSignedBy(t, "svc-ak", "svc-sk")
+	})
+	t.Run("env is used if there is no file", func(t *testing.T) {
+		assert.NoError(t, auth.LoadAkskAuth())
+		testCheckSignedBy(t, "ea", "es")
+		_, id, err := auth.CurrentProject()
+		assert.NoError(t, err)
+		assert.Equal(t, "ep", id)
+	})
+	t.Run("service cipher is used if env has none", func(t *testing.T) {
+		os.Unsetenv(auth.EnvAkskCustomCipher)
+		assert.NoError(t, archaius.Set(common.AKSKCustomCipher, "reverse"))
+		assert.NoError(t, auth.LoadAkskAuth())
+		testCheckSignedBy(t, "ea", "es")
+		assert.NoError(t, archaius.Delete(common.AKSKCustomCipher))
+
+		assert.NoError(t, archaius.Set("cse.credentials.akskCustomCipher", "reverse"))
+		defer archaius.Delete("cse.credentials.akskCustomCipher")
+		assert.NoError(t, auth.LoadAkskAuth())
+		testCheckSignedBy(t, "ea", "es")
+
+		c, _, err := auth.RetrieveCredential(auth.ProviderEnv)
+		assert.NoError(t, err)
+		assert.Empty(t, c.AkskCustomCipher, "bootstrap credential does not inherit service cipher")
+	})
+	t.Run("env is skipped if it is not set", func(t *testing.T) {
+		testWriteFile(t, credentialFilePath, "fa", "fs", "fp", "")
+		os.Unsetenv(auth.EnvAccessKey)
+		os.Unsetenv(auth.EnvSecretKey)
+		assert.NoError(t, auth.LoadAkskAuth())
+		testCheckAkAndProject(t, "fa", "fp")
+	})
+}
+
+func testCheckSignedBy(t *testing.T, ak, sk string) {
+	v, err := auth.NewVerifier(&auth.Credential{AccessKey: ak, SecretKey: sk})
+	assert.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	assert.NoError(t, httpclient.SignRequest(req))
+	_, err = v.Verify(req.Header.Get(auth.HeaderServiceAk), req.Header.Get(auth.HeaderServiceShaAKSK), req.Header.Get(auth.HeaderServiceProject))
+	assert.NoError(t, err, "sk is decrypted")
+}
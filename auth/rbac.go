@@ -0,0 +1,134 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/servicecenter"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	chassistls "github.com/go-chassis/go-chassis/v2/core/tls"
+)
+
+// TypeRBAC logs in service center with account name and password
+const TypeRBAC = "rbac"
+
+const (
+	keyAccountName     = "servicecomb.credentials.account.name"
+	keyAccountPassword = "servicecomb.credentials.account.password"
+)
+
+// LoadRBACAuth logs in service center of CSE engine with servicecomb.credentials.account,
+// and sets "Authorization: Bearer token" to requests.
+// if ak sk is loaded, requests are signed with ShaAKSK as well
+func LoadRBACAuth() error {
+	fetch, err := newRBACTokenFetcher()
+	if err != nil {
+		return err
+	}
+	t := NewCachedToken(TypeRBAC, fetch)
+	return setTokenAuth(t, GetRBACSignFunc(t))
+}
+
+// loadRBACAuthWithAKSK loads ak sk before rbac auth if there is one, like engine.Init does,
+// so that servicecomb.credentials.type rbac signs requests with both the token and ShaAKSK
+func loadRBACAuthWithAKSK() error {
+	err := LoadAkskAuth()
+	if err != nil && err != ErrAuthConfNotExist {
+		return err
+	}
+	watchAkskAuth()
+	return LoadRBACAuth()
+}
+
+// GetRBACSignFunc sets token to Authorization header, and ShaAKSK headers if ak sk is loaded
+func GetRBACSignFunc(t *CachedToken) SignRequest {
+	return ToSignRequest(Chain(FromSignRequest(signIfAKSKLoaded), NewTokenSigner(t, HeaderAuthorization, "Bearer ")))
+}
+
+// signIfAKSKLoaded signs with current ak sk, and leaves request unsigned if there is no ak sk
+func signIfAKSKLoaded(r *http.Request) error {
+	if err := signWithCurrentAuth(r); err != nil && err != ErrAuthNotLoaded {
+		return err
+	}
+	return nil
+}
+
+func newRBACTokenFetcher() (TokenFetcher, error) {
+	name := archaius.GetString(keyAccountName, "")
+	if name == "" {
+		return nil, errors.New(keyAccountName + " is empty")
+	}
+	// password can be encrypted just like sk, name is masked as ak
+	pwd, err := decryptSecretKey(scopeRBAC, &Credential{
+		AccessKey:        name,
+		SecretKey:        archaius.GetString(keyAccountPassword, ""),
+		AkskCustomCipher: serviceCipher(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.Split(config.GetRegistratorAddress(), ",")[0]
+	if endpoint == "" {
+		return nil, errors.New("service center address is empty")
+	}
+	tlsConfig, err := serviceCenterTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	// the account belongs to the engine of configured project
+	project, err := resolveProject(archaius.GetString(keyProjectV2, archaius.GetString(keyProject, "")))
+	if err != nil {
+		return nil, err
+	}
+	c, err := servicecenter.New(servicecenter.Options{
+		Endpoint: endpoint,
+		// the token is not ready yet, login request carries ak sk only
+		Signer:    signIfAKSKLoaded,
+		TLSConfig: tlsConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() (*Token, error) {
+		t, err := c.GetToken(name, pwd)
+		if err != nil {
+			return nil, err
+		}
+		return &Token{Value: t.Value, ExpiresAt: t.ExpiresAt, Project: project}, nil
+	}, nil
+}
+
+// serviceCenterTLSConfig uses the ssl config of registrator, which go chassis uses for the same service center,
+// or TLSConfig if there is no such ssl config
+func serviceCenterTLSConfig() (*tls.Config, error) {
+	c, _, err := chassistls.GetTLSConfigByService(registry.RTag, "", common.Consumer)
+	if err == nil {
+		return c, nil
+	}
+	if !chassistls.IsSSLConfigNotExist(err) {
+		return nil, err
+	}
+	return TLSConfig()
+}
@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRBACAuth(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v4/token", r.URL.Path)
+		assert.Equal(t, "ba1", r.Header.Get(auth.HeaderServiceAk))
+		b, _ := ioutil.ReadAll(r.Body)
+		a := make(map[string]string)
+		assert.NoError(t, json.Unmarshal(b, &a))
+		if a["name"] != "root" || a["password"] != "pwd" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token":"rbac-token"}`))
+	}))
+	defer s.Close()
+	config.GlobalDefinition.ServiceComb.Registry.Address = s.URL + ",https://127.0.0.1:30100"
+	testWriteFile(t, credentialFilePath, "ba1", "bs1", "bp1", "")
+	assert.NoError(t, auth.LoadAuth())
+
+	t.Log("engine enables RBAC after ak sk is loaded")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.account.name", "root"))
+	defer archaius.Delete("servicecomb.credentials.account.name")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.account.password", "pwd"))
+	defer archaius.Delete("servicecomb.credentials.account.password")
+	assert.NoError(t, auth.LoadRBACAuth())
+	r, _ := http.NewRequest("GET", "http://127.0.0.1", nil)
+	assert.NoError(t, httpclient.SignRequest(r))
+	assert.Equal(t, "Bearer rbac-token", r.Header.Get("Authorization"))
+	assert.Equal(t, "ba1", r.Header.Get(auth.HeaderServiceAk))
+
+	t.Log("token is scoped to configured project")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.project", "rbac-project"))
+	defer archaius.Delete("servicecomb.credentials.project")
+	assert.NoError(t, auth.LoadRBACAuth())
+	r, _ = http.NewRequestWithContext(auth.WithProject(context.Background(), "rbac-project"), "GET", "http://127.0.0.1", nil)
+	assert.NoError(t, httpclient.SignRequest(r))
+	assert.Equal(t, "Bearer rbac-token", r.Header.Get("Authorization"))
+	r, _ = http.NewRequestWithContext(auth.WithProject(context.Background(), "other"), "GET", "http://127.0.0.1", nil)
+	err := httpclient.SignRequest(r)
+	assert.True(t, errors.Is(err, auth.ErrTokenProjectMismatch), err)
+	assert.Empty(t, r.Header.Get("Authorization"))
+
+	t.Log("wrong password")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.account.password", "wrong"))
+	assert.Error(t, auth.LoadRBACAuth())
+	assert.NoError(t, archaius.Set("servicecomb.credentials.type", auth.TypeRBAC))
+	defer archaius.Delete("servicecomb.credentials.type")
+	assert.Error(t, auth.LoadAuth())
+}
+
+func TestLoadAuth_RBACAgreesWithExplain(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"rbac-token"}`))
+	}))
+	defer s.Close()
+	config.GlobalDefinition.ServiceComb.Registry.Address = s.URL
+	for k, v := range map[string]string{
+		"servicecomb.credentials.type":             auth.TypeRBAC,
+		"servicecomb.credentials.account.name":     "root",
+		"servicecomb.credentials.account.password": "pwd",
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+	sign := func() *http.Request {
+		r, _ := http.NewRequest("GET", "http://127.0.0.1", nil)
+		assert.NoError(t, httpclient.SignRequest(r))
+		return r
+	}
+
+	t.Run("with ak sk", func(t *testing.T) {
+		testWriteFile(t, credentialFilePath, "rea1", "res1", "rep1", "")
+		e, err := auth.Explain()
+		assert.NoError(t, err)
+		assert.Equal(t, auth.HeaderAuthorization+", "+auth.SignerShaAKSK, e.Signer)
+		assert.NoError(t, auth.LoadAuth())
+		r := sign()
+		assert.Equal(t, "Bearer rbac-token", r.Header.Get(auth.HeaderAuthorization))
+		assert.Equal(t, "rea1", r.Header.Get(auth.HeaderServiceAk))
+	})
+	t.Run("without ak sk", func(t *testing.T) {
+		assert.NoError(t, os.Remove(credentialFilePath))
+		auth.ResetAkskAuth()
+		e, err := auth.Explain()
+		assert.NoError(t, err)
+		assert.Equal(t, auth.HeaderAuthorization, e.Signer)
+		assert.NoError(t, auth.LoadAuth())
+		r := sign()
+		assert.Equal(t, "Bearer rbac-token", r.Header.Get(auth.HeaderAuthorization))
+		assert.Empty(t, r.Header.Get(auth.HeaderServiceAk))
+	})
+}
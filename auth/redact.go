@@ -0,0 +1,166 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// redaction policy of credential logs:
+// access keys and account names are masked by MaskSecret, so that they can still be recognized,
+// secret keys, passwords and security tokens are replaced by "******", in plain text or encrypted.
+// every credential loaded is registered, Redact removes them from any text, like errors with response body
+const redactedSecret = "******"
+
+type sensitiveValue struct {
+	value       string
+	replacement string
+}
+
+var (
+	sensitiveLock sync.Mutex
+	// sensitive holds values of each scope, a scope is a credential slot, like the service credential or a profile,
+	// a credential registered in a scope replaces the old one, so that rotated out credentials are not kept forever
+	sensitive = make(map[string][]sensitiveValue)
+	// redactor replaces all values in sensitive, it is rebuilt once sensitive changes
+	redactor atomic.Value
+)
+
+// credential scopes of redaction besides profiles and trusted credentials
+const (
+	scopeService          = "service"
+	scopeServiceSecondary = "service.secondary"
+	scopeBootstrap        = "bootstrap"
+	scopeIAMPassword      = "iam.password"
+	scopeIAMAKSK          = "iam.aksk"
+	scopeRBAC             = "rbac"
+	scopeExplain          = "explain"
+	scopeValidate         = "validate"
+	scopeCredential       = "credential"
+)
+
+// MaskSecret keeps the first and last 4 characters of a long value, so that it can be recognized in logs
+func MaskSecret(v string) string {
+	if len(v) <= 8 {
+		return "****"
+	}
+	return v[:4] + "****" + v[len(v)-4:]
+}
+
+func maskIfSet(v string) string {
+	if v == "" {
+		return ""
+	}
+	return MaskSecret(v)
+}
+
+// identifier returns a value which Redact masks
+func identifier(v string) sensitiveValue {
+	return sensitiveValue{value: v, replacement: MaskSecret(v)}
+}
+
+// secret returns a value which Redact hides
+func secret(v string) sensitiveValue {
+	return sensitiveValue{value: v, replacement: redactedSecret}
+}
+
+// registerSensitive replaces values of scope with values
+func registerSensitive(scope string, values ...sensitiveValue) {
+	updateSensitive(scope, values, false)
+}
+
+// addSensitive adds values to scope, it keeps values registered before
+func addSensitive(scope string, values ...sensitiveValue) {
+	updateSensitive(scope, values, true)
+}
+
+func updateSensitive(scope string, values []sensitiveValue, keep bool) {
+	sensitiveLock.Lock()
+	defer sensitiveLock.Unlock()
+	if keep {
+		values = append(append([]sensitiveValue(nil), sensitive[scope]...), values...)
+	}
+	sensitive[scope] = values
+	rebuildRedactor()
+}
+
+// rebuildRedactor builds a replacer of all values, longer values are replaced first,
+// a value registered as both identifier and secret is hidden
+func rebuildRedactor() {
+	replacements := make(map[string]string)
+	for _, values := range sensitive {
+		for _, v := range values {
+			if v.value == "" || replacements[v.value] == redactedSecret {
+				continue
+			}
+			replacements[v.value] = v.replacement
+		}
+	}
+	keys := make([]string, 0, len(replacements))
+	for k := range replacements {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k, replacements[k])
+	}
+	redactor.Store(strings.NewReplacer(pairs...))
+}
+
+// Redact replaces credentials loaded in this process with their masked value,
+// use it on any text which may carry credential before logging it
+func Redact(s string) string {
+	r, ok := redactor.Load().(*strings.Replacer)
+	if !ok {
+		return s
+	}
+	return r.Replace(s)
+}
+
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError returns an error whose message is redacted, errors.Is and errors.As still see the original one
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*redactedError); ok {
+		return err
+	}
+	return &redactedError{err: err, msg: Redact(err.Error())}
+}
@@ -0,0 +1,75 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/openlog"
+)
+
+const (
+	keyRefreshAhead = "servicecomb.credentials.refreshAhead"
+
+	// DefaultRefreshAhead is how long before expiry a temporary credential will be refreshed
+	DefaultRefreshAhead = 5 * time.Minute
+	// RefreshRetryInterval is the interval of retry, when the source has not returned a new credential yet
+	RefreshRetryInterval = 10 * time.Second
+)
+
+var (
+	refreshTimer *time.Timer
+	refreshLock  sync.Mutex
+)
+
+// refreshAhead returns servicecomb.credentials.refreshAhead, for example "10m"
+func refreshAhead() time.Duration {
+	v := archaius.GetString(keyRefreshAhead, "")
+	if v == "" {
+		return DefaultRefreshAhead
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s [%s], use default value %s", keyRefreshAhead, v, DefaultRefreshAhead))
+		return DefaultRefreshAhead
+	}
+	return d
+}
+
+// scheduleRefresh reloads a temporary credential from its source ahead of expiry,
+// a permanent credential cancels the scheduled refresh
+func scheduleRefresh(a *akskAuth) {
+	refreshLock.Lock()
+	defer refreshLock.Unlock()
+	if refreshTimer != nil {
+		refreshTimer.Stop()
+		refreshTimer = nil
+	}
+	if a.expiresAt.IsZero() {
+		return
+	}
+	d := time.Until(a.expiresAt) - refreshAhead()
+	if d <= 0 {
+		d = RefreshRetryInterval
+	}
+	openlog.Debug(fmt.Sprintf("temporary credential expires at %s, refresh after %s", a.expiresAt, d))
+	refreshTimer = time.AfterFunc(d, reloadAkskAuth)
+}
@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// tempProvider returns a new temporary credential each time
+type tempProvider struct {
+	mu  sync.Mutex
+	n   int
+	ttl time.Duration
+}
+
+func (p *tempProvider) Name() string {
+	return "temp"
+}
+
+func (p *tempProvider) Retrieve() (*auth.Credential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n++
+	return &auth.Credential{
+		AccessKey:     fmt.Sprintf("ta%d", p.n),
+		SecretKey:     "ts",
+		Project:       "tp",
+		SecurityToken: fmt.Sprintf("token%d", p.n),
+		ExpiresAt:     time.Now().Add(p.ttl),
+	}, nil
+}
+
+func TestTemporaryCredential(t *testing.T) {
+	testInitEnv(t)
+	p := &tempProvider{ttl: 300 * time.Millisecond}
+	auth.InstallCredentialProvider(p)
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "temp"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.refreshAhead", "200ms"))
+	defer archaius.Delete("servicecomb.credentials.providers")
+	defer archaius.Delete("servicecomb.credentials.refreshAhead")
+
+	assert.NoError(t, auth.LoadAkskAuth())
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	assert.NoError(t, httpclient.SignRequest(req))
+	// a reload triggered by config change may have retrieved a newer one
+	ak := req.Header.Get(auth.HeaderServiceAk)
+	assert.True(t, strings.HasPrefix(ak, "ta"))
+	assert.Equal(t, "token"+strings.TrimPrefix(ak, "ta"), req.Header.Get(auth.HeaderSecurityToken))
+
+	t.Run("refresh ahead of expiry", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			return testSignedAk(t) != ak
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+	t.Run("expired credential can not be refreshed", func(t *testing.T) {
+		p.mu.Lock()
+		p.ttl = -time.Second
+		p.mu.Unlock()
+		assert.Eventually(t, func() bool {
+			r, _ := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+			return httpclient.SignRequest(r) == auth.ErrCredentialExpired
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+}
@@ -0,0 +1,190 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/provider/huawei/env"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"gopkg.in/yaml.v2"
+)
+
+const keyRegions = "servicecomb.credentials.regions"
+
+// sources of project
+const (
+	ProjectFromEnv        = "env"
+	ProjectFromCredential = "credential"
+	ProjectFromURI        = "uri"
+	ProjectFromRegionEnv  = "regionEnv"
+	ProjectFromDefault    = "default"
+)
+
+// KnownEndpointDomains are domains of huawei cloud endpoints, whose hosts are <service>.<region>.<domain>
+var KnownEndpointDomains = []string{
+	"myhuaweicloud.com",
+	"myhwclouds.com",
+	"huaweicloud.com",
+	"myhuaweicloud.eu",
+}
+
+// regionPattern matches region names, like cn-north-4, ap-southeast-1 and ae-ad-1
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+[a-z]?$`)
+
+// RegionRule maps endpoints to a region, it is used for endpoints of custom domains or IP addresses.
+// Pattern is same as the one of EndpointRule
+type RegionRule struct {
+	Pattern string `yaml:"pattern"`
+	Region  string `yaml:"region"`
+}
+
+// Location is the region and project the service works in
+type Location struct {
+	Region  string
+	Project string
+	// Source tells where project comes from
+	Source string
+	// Reason explains how project is resolved, it tells why project falls back to default
+	Reason string
+}
+
+// ResolveLocation resolves region and project of the service, project is the one configured in credential.
+// project is the first one of
+// 1, env PAAS_PROJECT_NAME
+// 2, project configured in credential
+// 3, region of registry address, by servicecomb.credentials.regions or known huawei cloud endpoint domains
+// 4, env PAAS_REGION_NAME
+// 5, "default"
+// region is the one project named after, or env PAAS_REGION_NAME.
+// registry address is read only if no project is set
+func ResolveLocation(project string) (*Location, error) {
+	l, err := resolveProjectLocation(project)
+	if err != nil {
+		return nil, err
+	}
+	if l.Region = regionOfProject(l.Project); l.Region == "" {
+		l.Region = env.RegionName()
+	}
+	return l, nil
+}
+
+// regionOfProject returns the region a project named after, a sub project is <region>_<name>
+func regionOfProject(project string) string {
+	if r := strings.SplitN(project, "_", 2)[0]; regionPattern.MatchString(r) {
+		return r
+	}
+	return ""
+}
+
+func resolveProjectLocation(project string) (*Location, error) {
+	if v := env.ProjectName(); v != "" {
+		return &Location{Project: v, Source: ProjectFromEnv, Reason: "env PAAS_PROJECT_NAME is set"}, nil
+	}
+	if project != "" {
+		return &Location{Project: project, Source: ProjectFromCredential, Reason: "project is set in credential"}, nil
+	}
+	region, reason, err := regionOfAddresses(config.GetRegistratorAddress())
+	if err != nil {
+		return nil, err
+	}
+	if region != "" {
+		return &Location{Project: region, Source: ProjectFromURI, Reason: reason}, nil
+	}
+	if v := env.RegionName(); v != "" {
+		return &Location{Project: v, Source: ProjectFromRegionEnv, Reason: reason + ", use env PAAS_REGION_NAME"}, nil
+	}
+	return &Location{Project: common.DefaultValue, Source: ProjectFromDefault,
+		Reason: reason + ", and env PAAS_REGION_NAME is empty, use default project"}, nil
+}
+
+// RegionOfEndpoint returns region of an endpoint by servicecomb.credentials.regions or known huawei cloud endpoint domains,
+// reason tells how region is resolved, or why it can not be resolved
+func RegionOfEndpoint(endpoint string) (region, reason string, err error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid endpoint [%s]: %v", endpoint, err)
+	}
+	rules, err := getRegionsConfig()
+	if err != nil {
+		return "", "", err
+	}
+	for _, rule := range rules {
+		if matchEndpoint(rule.Pattern, u) {
+			return rule.Region, fmt.Sprintf("endpoint [%s] matches [%s] of %s", u.Host, rule.Pattern, keyRegions), nil
+		}
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range KnownEndpointDomains {
+		if !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		labels := strings.Split(strings.TrimSuffix(host, "."+domain), ".")
+		if r := labels[len(labels)-1]; regionPattern.MatchString(r) {
+			return r, fmt.Sprintf("endpoint [%s] is in region %s", u.Host, r), nil
+		}
+		return "", fmt.Sprintf("endpoint [%s] of %s contains no region", u.Host, domain), nil
+	}
+	return "", fmt.Sprintf("endpoint [%s] is not a huawei cloud endpoint, and matches no rule of %s", u.Host, keyRegions), nil
+}
+
+// regionOfAddresses returns the region of the first address which has one
+func regionOfAddresses(addresses string) (string, string, error) {
+	reasons := make([]string, 0)
+	for _, addr := range strings.Split(addresses, ",") {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		region, reason, err := RegionOfEndpoint(addr)
+		if err != nil {
+			return "", "", fmt.Errorf("get project from registry address failed: %w", err)
+		}
+		if region != "" {
+			return region, reason, nil
+		}
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) == 0 {
+		return "", "registry address is empty", nil
+	}
+	return "", strings.Join(reasons, ", "), nil
+}
+
+func getRegionsConfig() ([]RegionRule, error) {
+	rules := make([]RegionRule, 0)
+	v := archaius.Get(keyRegions)
+	if v == nil {
+		return rules, nil
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", keyRegions, err)
+	}
+	return rules, nil
+}
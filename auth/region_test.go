@@ -0,0 +1,91 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionOfEndpoint(t *testing.T) {
+	testInitEnv(t)
+	for endpoint, region := range map[string]string{
+		"https://cse.cn-north-1.myhwclouds.com:443":           "cn-north-1",
+		"https://cse.ap-southeast-1.myhuaweicloud.com":        "ap-southeast-1",
+		"https://servicecomb.cn-north-4.myhuaweicloud.com/v4": "cn-north-4",
+		"cse-sc.la-south-2.myhuaweicloud.com:30100":           "la-south-2",
+		"https://kms.ae-ad-1.myhuaweicloud.com":               "ae-ad-1",
+		"https://cse.myhuaweicloud.com":                       "",
+		"https://192.168.0.1:30100":                           "",
+		"http://cse:8080":                                     "",
+		"https://cse.cn-north-1.example.com":                  "",
+	} {
+		r, reason, err := auth.RegionOfEndpoint(endpoint)
+		assert.NoError(t, err)
+		assert.Equal(t, region, r, endpoint)
+		assert.NotEmpty(t, reason)
+	}
+	_, _, err := auth.RegionOfEndpoint(":://a+b")
+	assert.Error(t, err)
+
+	t.Log("region table")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.regions", []interface{}{
+		map[string]interface{}{"pattern": "192.168.0.*", "region": "cn-east-3"},
+		map[string]interface{}{"pattern": "https://cse.example.com/south", "region": "cn-south-1"},
+	}))
+	defer archaius.Delete("servicecomb.credentials.regions")
+	r, reason, err := auth.RegionOfEndpoint("https://192.168.0.1:30100")
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-east-3", r)
+	assert.Contains(t, reason, "servicecomb.credentials.regions")
+	r, _, err = auth.RegionOfEndpoint("https://cse.example.com/south/v4")
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-south-1", r)
+	r, _, err = auth.RegionOfEndpoint("https://cse.example.com/north/v4")
+	assert.NoError(t, err)
+	assert.Equal(t, "", r)
+}
+
+func TestResolveLocation(t *testing.T) {
+	testInitEnv(t)
+	config.GlobalDefinition.ServiceComb.Registry.Address = "http://127.0.0.1:30100,https://cse.ap-southeast-1.myhuaweicloud.com"
+	l, err := auth.ResolveLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, &auth.Location{Region: "ap-southeast-1", Project: "ap-southeast-1", Source: auth.ProjectFromURI,
+		Reason: "endpoint [cse.ap-southeast-1.myhuaweicloud.com] is in region ap-southeast-1"}, l)
+
+	l, err = auth.ResolveLocation("cn-north-4_sub")
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-north-4", l.Region)
+	assert.Equal(t, "cn-north-4_sub", l.Project)
+	assert.Equal(t, auth.ProjectFromCredential, l.Source)
+
+	l, err = auth.ResolveLocation("p1")
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", l.Project)
+	assert.Equal(t, "", l.Region, "p1 is not named after a region")
+
+	t.Log("fall back to default")
+	config.GlobalDefinition.ServiceComb.Registry.Address = ""
+	l, err = auth.ResolveLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", l.Project)
+	assert.Equal(t, auth.ProjectFromDefault, l.Source)
+	assert.Contains(t, l.Reason, "registry address is empty")
+	config.GlobalDefinition.ServiceComb.Registry.Address = "https://cse.example.com"
+	l, err = auth.ResolveLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", l.Project)
+	assert.Contains(t, l.Reason, "[cse.example.com] is not a huawei cloud endpoint")
+
+	t.Log("invalid registry address")
+	config.GlobalDefinition.ServiceComb.Registry.Address = ":://a+b"
+	_, err = auth.ResolveLocation("")
+	assert.Error(t, err)
+	l, err = auth.ResolveLocation("p1")
+	assert.NoError(t, err, "registry address is not used")
+	assert.Equal(t, "p1", l.Project)
+	config.GlobalDefinition.ServiceComb.Registry.Address = ""
+}
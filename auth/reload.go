@@ -0,0 +1,296 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-archaius/event"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/openlog"
+)
+
+// ErrAuthNotLoaded means no credential has been loaded yet
+var ErrAuthNotLoaded = errors.New("auth is not loaded")
+
+// ErrCredentialExpired means the temporary credential is expired and can not be refreshed
+var ErrCredentialExpired = errors.New("credential is expired")
+
+var (
+	currentAuth atomic.Value
+	watchOnce   sync.Once
+	reloadMutex sync.Mutex
+)
+
+// akskAuth is a loaded credential together with its sign func
+type akskAuth struct {
+	provider      string
+	signer        string
+	ak            string
+	shaAKSK       string
+	project       string
+	projectID     string
+	securityToken string
+	expiresAt     time.Time
+	sign          SignRequest
+	// validate checks this credential by the cloud service of servicecomb.credentials.validateTarget
+	validate func() error
+	// lookupProject returns id of a project name with this credential
+	lookupProject func(name string) (string, error)
+	// secondary is used once this one is rejected
+	secondary *akskAuth
+}
+
+func (a *akskAuth) equal(b *akskAuth) bool {
+	return a.signer == b.signer && a.ak == b.ak && a.shaAKSK == b.shaAKSK &&
+		a.project == b.project && a.projectID == b.projectID && a.securityToken == b.securityToken &&
+		a.expiresAt.Equal(b.expiresAt) && equalSecondary(a.secondary, b.secondary)
+}
+
+func equalSecondary(a, b *akskAuth) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.equal(b)
+}
+
+// event returns a credential event of a
+func (a *akskAuth) event(t CredentialEventType) CredentialEvent {
+	return CredentialEvent{
+		Type:           t,
+		CredentialType: TypeAKSK,
+		Provider:       a.provider,
+		AccessKey:      MaskSecret(a.ak),
+		Project:        a.project,
+	}
+}
+
+func (a *akskAuth) expired() bool {
+	return !a.expiresAt.IsZero() && !time.Now().Before(a.expiresAt)
+}
+
+// signWithCurrentAuth is assigned to httpclient.SignRequest,
+// it always signs with the latest loaded credential
+func signWithCurrentAuth(r *http.Request) error {
+	a, err := loadedAkskAuth()
+	if err != nil {
+		return err
+	}
+	recordSignInfo(r, func(info *SignInfo) {
+		info.Type, info.Provider, info.AccessKey, info.Project = TypeAKSK, a.provider, a.ak, projectOf(r, a.project)
+		if info.Project == a.project {
+			info.ProjectID = a.projectID
+		}
+	})
+	return a.sign(r)
+}
+
+// loadedAkskAuth returns current credential, refreshes it if it is expired
+func loadedAkskAuth() (*akskAuth, error) {
+	a, ok := currentAuth.Load().(*akskAuth)
+	if !ok {
+		return nil, ErrAuthNotLoaded
+	}
+	if a.expired() {
+		if a = refreshExpiredAuth(); a.expired() {
+			return nil, ErrCredentialExpired
+		}
+	}
+	return a, nil
+}
+
+// IdentityHeaders returns ShaAKSK headers of current credential,
+// transports other than http, like highway and grpc, carry them in their own headers or metadata
+func IdentityHeaders() (map[string]string, error) {
+	return IdentityHeadersContext(context.Background())
+}
+
+// IdentityHeadersContext is same as IdentityHeaders, besides it uses the project and profile of ctx
+func IdentityHeadersContext(ctx context.Context) (map[string]string, error) {
+	if p, ok := ProfileFromContext(ctx); ok {
+		return profileIdentityHeaders(ctx, p)
+	}
+	a, err := loadedAkskAuth()
+	if err != nil {
+		return nil, err
+	}
+	project := a.project
+	if p, ok := ProjectFromContext(ctx); ok {
+		project = p
+	}
+	h := map[string]string{
+		HeaderServiceAk:      a.ak,
+		HeaderServiceShaAKSK: a.shaAKSK,
+		HeaderServiceProject: project,
+	}
+	if a.securityToken != "" {
+		h[HeaderSecurityToken] = a.securityToken
+	}
+	return h, nil
+}
+
+// refreshExpiredAuth reloads credential if current one is expired,
+// concurrent callers wait for the same reload
+func refreshExpiredAuth() *akskAuth {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	a := currentAuth.Load().(*akskAuth)
+	if !a.expired() {
+		return a
+	}
+	reloadAkskAuthLocked()
+	return currentAuth.Load().(*akskAuth)
+}
+
+// reloadAkskAuth loads credential again, and swaps the sign func if credential changed.
+// if new credential is invalid, the old one is kept
+func reloadAkskAuth() {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	resetVerifier()
+	reloadAkskAuthLocked()
+}
+
+func reloadAkskAuthLocked() {
+	old, ok := currentAuth.Load().(*akskAuth)
+	a, err := newAkskAuth()
+	if err != nil {
+		e := CredentialEvent{Type: EventRefreshFailed, CredentialType: TypeAKSK, Error: err.Error()}
+		if ok {
+			e = old.event(EventRefreshFailed)
+			e.Error = "keep using the old credential: " + err.Error()
+			scheduleRefresh(old)
+		}
+		emitCredentialEvent(e)
+		return
+	}
+	defer scheduleRefresh(a)
+	defer scheduleProbe()
+	// a promoted secondary keeps working until config changes
+	if ok && (old.equal(a) || (a.secondary != nil && old.equal(a.promote()))) {
+		return
+	}
+	currentAuth.Store(a)
+	if !ok {
+		emitCredentialEvent(a.event(EventLoaded))
+		// a token sign func signs with ak sk as well once it is loaded
+		if !tokenAuthEnabled() {
+			setSignRequest(signWithCurrentAuth)
+		}
+		return
+	}
+	e := a.event(EventRotated)
+	e.OldAccessKey = MaskSecret(old.ak)
+	emitCredentialEvent(e)
+}
+
+// watchAkskAuth watches ${CIPHER_ROOT}/certificate.yaml and credential configs,
+// reload credential when any of them changes
+func watchAkskAuth() {
+	watchOnce.Do(func() {
+		if err := archaius.RegisterListener(&credentialListener{}, credentialKeys()...); err != nil {
+			openlog.Error("can not watch credential config: " + err.Error())
+		}
+		if err := watchCredentialFile(); err != nil {
+			openlog.Error("can not watch credential file: " + err.Error())
+		}
+	})
+}
+
+func credentialKeys() []string {
+	return []string{keyAKV2, keySKV2, keyProjectV2, common.AKSKCustomCipher, keySecondaryAK, keySecondarySK, keySecondaryCipher,
+		keyAK, keySK, keyProject, keyCipher, keyProviders, keySigner, keyTrusted, keySecretDir, keyCSMS}
+}
+
+// credentialListener reload credential when credential configs change
+type credentialListener struct{}
+
+// Event implements event.Listener
+func (l *credentialListener) Event(e *event.Event) {
+	openlog.Info(fmt.Sprintf("credential config [%s] changed", e.Key))
+	reloadAkskAuth()
+}
+
+// credentialFileDelay is how long to wait for more changes after a credential file changes,
+// so that credential is reloaded once after files of a credential are all written
+const credentialFileDelay = 200 * time.Millisecond
+
+// watchCredentialFile watches the dir of credential file rather than file itself,
+// because the file may be replaced instead of written.
+// both ${CIPHER_ROOT} and secret directory are watched
+func watchCredentialFile() error {
+	dirs := make([]string, 0, 2)
+	for _, dir := range []string{os.Getenv(CipherRootEnv), secretDir()} {
+		if dir == "" || (len(dirs) > 0 && dirs[0] == dir) {
+			continue
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := w.Add(dir); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	go func() {
+		defer w.Close()
+		var reload *time.Timer
+		for {
+			select {
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if e.Op == fsnotify.Chmod || (filepath.Base(e.Name) != KeytoolAkskFile && !isSecretDirFile(e.Name)) {
+					continue
+				}
+				openlog.Info(fmt.Sprintf("credential file changed: %s", e))
+				if reload != nil {
+					reload.Stop()
+				}
+				reload = time.AfterFunc(credentialFileDelay, reloadAkskAuth)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				openlog.Error("watch credential file error: " + Redact(err.Error()))
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,79 @@
+package auth_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSignedAk(t *testing.T) string {
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, httpclient.SignRequest(req))
+	return req.Header.Get(auth.HeaderServiceAk)
+}
+
+// testInitEnv prepares chassis home and cipher root, returns the credential file path
+func testInitEnv(t *testing.T) string {
+	authTestDir := filepath.Join(os.Getenv("GOPATH"), "test", "auth")
+	cipherRootDir := filepath.Join(authTestDir, "cipher")
+	chassisConf := filepath.Join(authTestDir, "conf")
+	os.Setenv("CHASSIS_HOME", authTestDir)
+	os.Setenv(auth.CipherRootEnv, cipherRootDir)
+	assert.NoError(t, os.MkdirAll(chassisConf, 0700))
+	assert.NoError(t, os.MkdirAll(cipherRootDir, 0700))
+	testWriteFile(t, filepath.Join(chassisConf, "chassis.yaml"), "", "", "", "")
+	os.Create(filepath.Join(chassisConf, "microservice.yaml"))
+	config.InitArchaius()
+	// reloads triggered by the last test may be still reading it
+	if config.GlobalDefinition == nil {
+		config.GlobalDefinition = &model.GlobalCfg{}
+	}
+	return filepath.Join(cipherRootDir, auth.KeytoolAkskFile)
+}
+
+func TestLoadAuth_Rotate(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "ra1", "rs1", "rp1", "")
+	assert.NoError(t, auth.LoadAuth())
+	assert.Equal(t, "ra1", testSignedAk(t))
+
+	t.Log("rotate credential file")
+	testWriteFile(t, credentialFilePath, "ra2", "rs2", "rp2", "")
+	assert.Eventually(t, func() bool {
+		return testSignedAk(t) == "ra2"
+	}, 3*time.Second, 50*time.Millisecond)
+
+	t.Log("keep old credential if the new one is invalid")
+	testWriteFile(t, credentialFilePath, "ra3", "", "rp3", "")
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, "ra2", testSignedAk(t))
+}
+
+func TestLoadAuth_ChangeSigner(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "sa1", "ss1", "sp1", "")
+	assert.NoError(t, auth.LoadAuth())
+	signed := func() string {
+		r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080", nil)
+		assert.NoError(t, httpclient.SignRequest(r))
+		return r.Header.Get(auth.HeaderAuthorization)
+	}
+	assert.Empty(t, signed())
+
+	assert.NoError(t, archaius.Set("servicecomb.credentials.signer", auth.SignerAPIG))
+	defer archaius.Delete("servicecomb.credentials.signer")
+	assert.Eventually(t, func() bool {
+		return strings.HasPrefix(signed(), auth.APIGAlgorithm)
+	}, 3*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,167 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/openlog"
+)
+
+const (
+	keySecondaryAK            = "servicecomb.credentials.secondary.accessKey"
+	keySecondarySK            = "servicecomb.credentials.secondary.secretKey"
+	keySecondaryCipher        = "servicecomb.credentials.secondary.akskCustomCipher"
+	keySecondaryProbeInterval = "servicecomb.credentials.secondary.probeInterval"
+
+	// DefaultSecondaryProbeInterval is how often the primary credential is validated while there is a secondary one
+	DefaultSecondaryProbeInterval = time.Minute
+)
+
+var (
+	probeTimer *time.Timer
+	probeLock  sync.Mutex
+)
+
+// secondaryCredential returns the secondary credential of c, it works in the same project,
+// and it is decrypted by the cipher of c if it has no cipher of its own
+func (c *Credential) secondaryCredential() *Credential {
+	s := *c.Secondary
+	s.Project, s.ProjectID, s.Secondary = c.Project, c.ProjectID, nil
+	if s.AkskCustomCipher == "" {
+		s.AkskCustomCipher = c.AkskCustomCipher
+	}
+	return &s
+}
+
+// promote returns a credential whose primary is the secondary of a, a becomes its secondary,
+// so that it can be promoted back if the rotation goes the other way
+func (a *akskAuth) promote() *akskAuth {
+	old := *a
+	old.secondary = nil
+	p := *a.secondary
+	p.secondary = &old
+	return &p
+}
+
+// promoteSecondary makes the secondary credential primary, if the primary ak is rejected.
+// it returns true if a request signed with ak should be signed again,
+// which is also true if a concurrent request has promoted the secondary
+func promoteSecondary(ak string) bool {
+	if ak == "" {
+		return false
+	}
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	a, ok := currentAuth.Load().(*akskAuth)
+	if !ok || a.secondary == nil {
+		return false
+	}
+	if a.ak != ak {
+		return a.secondary.ak == ak
+	}
+	p := a.promote()
+	currentAuth.Store(p)
+	e := p.event(EventPromoted)
+	e.OldAccessKey = MaskSecret(a.ak)
+	emitCredentialEvent(e)
+	scheduleProbe()
+	return true
+}
+
+// signedAccessKey returns the ak which signed req. requests signed by httpclient.SignRequest have no sign info,
+// their ak is read from headers, it is compared with the ak loaded by LoadAuth before promotion
+func signedAccessKey(req *http.Request) string {
+	info, ok := SignInfoFromContext(req.Context())
+	if !ok || info.Type == "" {
+		return signedAccessKeyOfHeaders(req.Header)
+	}
+	if info.Type != TypeAKSK || info.Profile != "" {
+		return ""
+	}
+	return info.AccessKey
+}
+
+// signedAccessKeyOfHeaders reads ak of ShaAKSK headers, or SDK-HMAC-SHA256 Authorization header
+func signedAccessKeyOfHeaders(h http.Header) string {
+	if ak := h.Get(HeaderServiceAk); ak != "" {
+		return ak
+	}
+	v := h.Get(HeaderAuthorization)
+	if !strings.HasPrefix(v, APIGAlgorithm+" ") {
+		return ""
+	}
+	for _, kv := range strings.Split(strings.TrimPrefix(v, APIGAlgorithm+" "), ",") {
+		if ak := strings.TrimPrefix(strings.TrimSpace(kv), "Access="); ak != strings.TrimSpace(kv) {
+			return ak
+		}
+	}
+	return ""
+}
+
+func secondaryProbeInterval() time.Duration {
+	v := archaius.GetString(keySecondaryProbeInterval, "")
+	if v == "" {
+		return DefaultSecondaryProbeInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s [%s], use default value %s", keySecondaryProbeInterval, v, DefaultSecondaryProbeInterval))
+		return DefaultSecondaryProbeInterval
+	}
+	return d
+}
+
+// scheduleProbe validates the primary credential later, if there is a secondary one.
+// clients of registry and config center do not retry rejected requests,
+// so the secondary is promoted by probing, once the primary is rejected
+func scheduleProbe() {
+	probeLock.Lock()
+	defer probeLock.Unlock()
+	if probeTimer != nil {
+		probeTimer.Stop()
+		probeTimer = nil
+	}
+	if a, ok := currentAuth.Load().(*akskAuth); !ok || a.secondary == nil {
+		return
+	}
+	probeTimer = time.AfterFunc(secondaryProbeInterval(), probePrimary)
+}
+
+// probePrimary promotes the secondary credential if the primary one is rejected
+func probePrimary() {
+	a, ok := currentAuth.Load().(*akskAuth)
+	if !ok || a.secondary == nil {
+		return
+	}
+	err := a.validate()
+	if isRejection(err) {
+		openlog.Warn(Redact(err.Error()))
+		if promoteSecondary(a.ak) {
+			return
+		}
+	} else if err != nil {
+		openlog.Debug("can not probe primary credential: " + Redact(err.Error()))
+	}
+	scheduleProbe()
+}
@@ -0,0 +1,202 @@
+package auth_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigningTransport_PromoteSecondary(t *testing.T) {
+	var hits int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.Header.Get(auth.HeaderServiceAk) != "sak2" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer s.Close()
+	credentialFilePath := testInitEnv(t)
+	assert.NoError(t, ioutil.WriteFile(credentialFilePath, []byte(`
+servicecomb:
+  credentials:
+    accessKey: sak1
+    secretKey: ssk1
+    project: sp
+    secondary:
+      accessKey: sak2
+      secretKey: ssk2
+`), 0600))
+	assert.NoError(t, auth.LoadAkskAuth())
+
+	v, err := auth.LoadVerifier()
+	assert.NoError(t, err)
+	h, err := auth.IdentityHeaders()
+	assert.NoError(t, err)
+	assert.Equal(t, "sak1", h[auth.HeaderServiceAk])
+	_, err = v.VerifyHeaders(h)
+	assert.NoError(t, err)
+
+	var events []auth.CredentialEvent
+	cancel := auth.SubscribeCredentialEvents(func(e auth.CredentialEvent) {
+		if e.Type == auth.EventPromoted {
+			events = append(events, e)
+		}
+	})
+	defer cancel()
+	c := &http.Client{Transport: auth.NewSigningTransport(nil, nil)}
+	get := func() int {
+		resp, err := c.Get(s.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, get())
+	assert.Equal(t, int32(2), atomic.SwapInt32(&hits, 0), "retry with secondary")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, auth.MaskSecret("sak2"), events[0].AccessKey)
+		assert.Equal(t, auth.MaskSecret("sak1"), events[0].OldAccessKey)
+	}
+
+	assert.Equal(t, http.StatusOK, get())
+	assert.Equal(t, int32(1), atomic.SwapInt32(&hits, 0), "secondary is promoted")
+	h, err = auth.IdentityHeaders()
+	assert.NoError(t, err)
+	assert.Equal(t, "sak2", h[auth.HeaderServiceAk])
+	assert.Equal(t, "sp", h[auth.HeaderServiceProject])
+	_, err = v.VerifyHeaders(h)
+	assert.NoError(t, err, "secondary is trusted as well")
+}
+
+func TestPromoteSecondary_Clients(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(auth.HeaderServiceAk) != "cak2" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer s.Close()
+	credentialFilePath := testInitEnv(t)
+	assert.NoError(t, ioutil.WriteFile(credentialFilePath, []byte(`
+servicecomb:
+  credentials:
+    accessKey: cak1
+    secretKey: csk1
+    project: cp
+    secondary:
+      accessKey: cak2
+      secretKey: csk2
+`), 0600))
+	assert.NoError(t, auth.LoadAkskAuth())
+
+	// signed by httpclient.SignRequest, like clients of registry and config center
+	c, err := resign.New(nil)
+	assert.NoError(t, err)
+	resp, err := c.Get(context.Background(), s.URL, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	h, err := auth.IdentityHeaders()
+	assert.NoError(t, err)
+	assert.Equal(t, "cak2", h[auth.HeaderServiceAk])
+}
+
+func TestPromoteSecondary_Forbidden(t *testing.T) {
+	var code int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(auth.HeaderServiceAk) == "fak2" {
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		if atomic.LoadInt32(&code) == 0 {
+			w.Write([]byte(`{"error_msg":"The IAM user is not authorized to access the API","error_code":"APIGW.0302"}`))
+			return
+		}
+		w.Write([]byte(`{"error_msg":"Incorrect IAM authentication information: verify aksk signature fail","error_code":"APIGW.0301"}`))
+	}))
+	defer s.Close()
+	credentialFilePath := testInitEnv(t)
+	assert.NoError(t, ioutil.WriteFile(credentialFilePath, []byte(`
+servicecomb:
+  credentials:
+    accessKey: fak1
+    secretKey: fsk1
+    project: fp
+    secondary:
+      accessKey: fak2
+      secretKey: fsk2
+`), 0600))
+	assert.NoError(t, auth.LoadAkskAuth())
+	c := &http.Client{Transport: auth.NewSigningTransport(nil, nil)}
+
+	t.Run("permission denied leaves active key unchanged", func(t *testing.T) {
+		resp, err := c.Get(s.URL)
+		assert.NoError(t, err)
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Contains(t, string(b), "APIGW.0302", "body is kept for caller")
+		h, err := auth.IdentityHeaders()
+		assert.NoError(t, err)
+		assert.Equal(t, "fak1", h[auth.HeaderServiceAk])
+	})
+	t.Run("wrong signature promotes secondary", func(t *testing.T) {
+		atomic.StoreInt32(&code, 1)
+		resp, err := c.Get(s.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		h, err := auth.IdentityHeaders()
+		assert.NoError(t, err)
+		assert.Equal(t, "fak2", h[auth.HeaderServiceAk])
+	})
+}
+
+func TestPromoteSecondary_Probe(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get(auth.HeaderAuthorization), "Access=pak1,") {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error_msg":"Incorrect IAM authentication information: ak pak1 not exist","error_code":"APIGW.0301"}`))
+			return
+		}
+		w.Write([]byte(`{"projects":[{"id":"ppid","name":"cn-north-4_pp"}]}`))
+	}))
+	defer s.Close()
+	credentialFilePath := testInitEnv(t)
+	for k, v := range map[string]string{
+		"servicecomb.credentials.iam.endpoint":            s.URL,
+		"servicecomb.credentials.secondary.probeInterval": "20ms",
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+	assert.NoError(t, ioutil.WriteFile(credentialFilePath, []byte(`
+servicecomb:
+  credentials:
+    accessKey: pak1
+    secretKey: psk1
+    project: cn-north-4_pp
+    secondary:
+      accessKey: pak2
+      secretKey: psk2
+`), 0600))
+	assert.NoError(t, auth.LoadAkskAuth())
+	defer func() {
+		testWriteFile(t, credentialFilePath, "pak2", "psk2", "cn-north-4_pp", "")
+		assert.NoError(t, auth.LoadAkskAuth(), "stop probing")
+	}()
+
+	assert.Eventually(t, func() bool {
+		h, err := auth.IdentityHeaders()
+		return err == nil && h[auth.HeaderServiceAk] == "pak2"
+	}, 3*time.Second, 20*time.Millisecond, "secondary is promoted without any rejected request")
+}
@@ -28,8 +28,15 @@ import (
 //SignRequest inject auth related header and sign this request so that this request can access to huawei cloud
 type SignRequest func(*http.Request) error
 
-// GetSignFunc sets and initializes the ak/sk auth func
+// GetSignFunc sets and initializes the ak/sk auth func,
+// the body is signed in the payload mode of request context or servicecomb.credentials.payload
 func GetSignFunc(ak, sk, project string) (SignRequest, error) {
+	return GetTemporarySignFunc(ak, sk, "", project)
+}
+
+// GetTemporarySignFunc is same as GetSignFunc,
+// besides it carries the security token of a temporary credential
+func GetTemporarySignFunc(ak, sk, securityToken, project string) (SignRequest, error) {
 	s := &hws_cloud.Signer{
 		AccessKey: ak,
 		SecretKey: sk,
@@ -37,7 +44,7 @@ func GetSignFunc(ak, sk, project string) (SignRequest, error) {
 		Region:    "",
 	}
 
-	shaAKSKSignFunc, err := GetShaAKSKSignFunc(ak, sk, project)
+	shaAKSKSignFunc, err := GetTemporaryShaAKSKSignFunc(ak, sk, securityToken, project)
 	if err != nil {
 		return nil, err
 	}
@@ -46,12 +53,20 @@ func GetSignFunc(ak, sk, project string) (SignRequest, error) {
 		if err := shaAKSKSignFunc(r); err != nil {
 			return err
 		}
-		return s.Sign(r)
+		setSigningTime(r)
+		return signPayload(s, r)
 	}, nil
 }
 
 // GetShaAKSKSignFunc sets and initializes the ak/sk auth func
 func GetShaAKSKSignFunc(ak, sk, project string) (SignRequest, error) {
+	return GetTemporaryShaAKSKSignFunc(ak, sk, "", project)
+}
+
+// GetTemporaryShaAKSKSignFunc is same as GetShaAKSKSignFunc,
+// besides it sets the security token of a temporary credential to X-Security-Token.
+// project is the default one, a request is signed for the project of its context set by WithProject
+func GetTemporaryShaAKSKSignFunc(ak, sk, securityToken, project string) (SignRequest, error) {
 	shaAKSK, err := genShaAKSK(sk, ak)
 	if err != nil {
 		return nil, err
@@ -63,7 +78,10 @@ func GetShaAKSKSignFunc(ak, sk, project string) (SignRequest, error) {
 		}
 		r.Header.Set(HeaderServiceAk, ak)
 		r.Header.Set(HeaderServiceShaAKSK, shaAKSK)
-		r.Header.Set(HeaderServiceProject, project)
+		r.Header.Set(HeaderServiceProject, projectOf(r, project))
+		if securityToken != "" {
+			r.Header.Set(HeaderSecurityToken, securityToken)
+		}
 		return nil
 	}, nil
 }
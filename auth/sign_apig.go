@@ -0,0 +1,235 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APIG signing, see https://support.huaweicloud.com/devg-apisign/api-sign-algorithm.html
+const (
+	APIGAlgorithm       = "SDK-HMAC-SHA256"
+	HeaderSdkDate       = "X-Sdk-Date"
+	HeaderAuthorization = "Authorization"
+
+	sdkDateFormat = "20060102T150405Z"
+	headerHost    = "host"
+)
+
+// APIGSigner signs request with SDK-HMAC-SHA256, which is accepted by huawei cloud APIG and IAM fronted APIs
+type APIGSigner struct {
+	AccessKey     string
+	SecretKey     string
+	SecurityToken string
+}
+
+// GetAPIGSignFunc sets and initializes the APIG SDK-HMAC-SHA256 auth func
+func GetAPIGSignFunc(ak, sk, securityToken string) (SignRequest, error) {
+	if ak == "" || sk == "" {
+		return nil, errors.New("ak or sk is empty")
+	}
+	s := &APIGSigner{
+		AccessKey:     ak,
+		SecretKey:     sk,
+		SecurityToken: securityToken,
+	}
+	return s.Sign, nil
+}
+
+// Sign sets X-Sdk-Date and Authorization header,
+// an existing X-Sdk-Date is used as the signing time
+func (s *APIGSigner) Sign(r *http.Request) error {
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Del(HeaderAuthorization)
+	if s.SecurityToken != "" {
+		r.Header.Set(HeaderSecurityToken, s.SecurityToken)
+	}
+	t, err := time.Parse(sdkDateFormat, r.Header.Get(HeaderSdkDate))
+	if err != nil {
+		t = signingTime()
+		r.Header.Set(HeaderSdkDate, t.UTC().Format(sdkDateFormat))
+	}
+	payloadHash, err := hashPayload(r)
+	if err != nil {
+		return err
+	}
+	signedHeaders := apigSignedHeaders(r)
+	canonicalRequest := apigCanonicalRequest(r, signedHeaders, payloadHash)
+	stringToSign := apigStringToSign(canonicalRequest, t)
+	signature, err := hmacSHA256([]byte(s.SecretKey), stringToSign)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(HeaderAuthorization, fmt.Sprintf("%s Access=%s, SignedHeaders=%s, Signature=%x",
+		APIGAlgorithm, s.AccessKey, strings.Join(signedHeaders, ";"), signature))
+	return nil
+}
+
+// hashPayload returns hex encoded sha256 of body, body is restored so that it can be sent later
+func hashPayload(r *http.Request) (string, error) {
+	var body []byte
+	var err error
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		body, err = ioutil.ReadAll(rc)
+		if err != nil {
+			return "", err
+		}
+	} else if r.Body != nil {
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	h := sha256.Sum256(body)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// apigSignedHeaders returns lower case names of all headers and host, sorted
+func apigSignedHeaders(r *http.Request) []string {
+	headers := []string{headerHost}
+	for k := range r.Header {
+		k = strings.ToLower(k)
+		if k == headerHost || k == strings.ToLower(HeaderAuthorization) {
+			continue
+		}
+		headers = append(headers, k)
+	}
+	sort.Strings(headers)
+	return headers
+}
+
+func apigCanonicalRequest(r *http.Request, signedHeaders []string, payloadHash string) string {
+	return strings.Join([]string{
+		r.Method,
+		apigCanonicalURI(r),
+		apigCanonicalQueryString(r),
+		apigCanonicalHeaders(r, signedHeaders),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// apigCanonicalURI escapes each segment of path, and always ends with "/"
+func apigCanonicalURI(r *http.Request) string {
+	segments := strings.Split(r.URL.Path, "/")
+	for i, v := range segments {
+		segments[i] = apigEscape(v)
+	}
+	uri := strings.Join(segments, "/")
+	if !strings.HasSuffix(uri, "/") {
+		uri = uri + "/"
+	}
+	return uri
+}
+
+// apigCanonicalQueryString sorts query by key then value
+func apigCanonicalQueryString(r *http.Request) string {
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]string, 0, len(query))
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			kvs = append(kvs, apigEscape(k)+"="+apigEscape(v))
+		}
+	}
+	return strings.Join(kvs, "&")
+}
+
+// apigCanonicalHeaders returns "name:value\n" of each signed header
+func apigCanonicalHeaders(r *http.Request, signedHeaders []string) string {
+	header := make(map[string][]string, len(r.Header))
+	for k, v := range r.Header {
+		header[strings.ToLower(k)] = append([]string(nil), v...)
+	}
+	var b strings.Builder
+	for _, k := range signedHeaders {
+		values := header[k]
+		if k == headerHost {
+			values = []string{requestHost(r)}
+		}
+		sort.Strings(values)
+		for _, v := range values {
+			b.WriteString(k + ":" + strings.TrimSpace(v) + "\n")
+		}
+	}
+	return b.String()
+}
+
+func apigStringToSign(canonicalRequest string, t time.Time) string {
+	h := sha256.Sum256([]byte(canonicalRequest))
+	return fmt.Sprintf("%s\n%s\n%x", APIGAlgorithm, t.UTC().Format(sdkDateFormat), h)
+}
+
+func hmacSHA256(key []byte, data string) ([]byte, error) {
+	h := hmac.New(sha256.New, key)
+	if _, err := h.Write([]byte(data)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func requestHost(r *http.Request) string {
+	if r.Host != "" {
+		return r.Host
+	}
+	return r.URL.Host
+}
+
+// apigEscape percent-encodes everything except unreserved characters
+func apigEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
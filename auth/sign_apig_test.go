@@ -0,0 +1,65 @@
+package auth_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIGSigner_Sign(t *testing.T) {
+	t.Run("example of huawei cloud document", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "https://service.region.example.com/v1/77b6a44cba5143ab91d13ab9a8ff44fd/vpcs?marker=13551d6b-755d-4757-b956-536f674975c0&limit=2", nil)
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(auth.HeaderSdkDate, "20191115T033655Z")
+		s := &auth.APIGSigner{AccessKey: "QTWAOYTTINDUT2QVKYUC", SecretKey: "MFyfvK41ba2giqM7Uio6PznpdUKGpownRZlmVmHc"}
+		assert.NoError(t, s.Sign(r))
+		assert.Equal(t, "SDK-HMAC-SHA256 Access=QTWAOYTTINDUT2QVKYUC, SignedHeaders=content-type;host;x-sdk-date, "+
+			"Signature=7be6668032f70418fcc22abc52071e57aff61b84a1d2381bb430d6870f4f6ebe", r.Header.Get(auth.HeaderAuthorization))
+	})
+	t.Run("escape path and query, hash body, sign security token", func(t *testing.T) {
+		r, err := http.NewRequest("POST", "https://example.com:8443/v1/p%20a/th?b=*+x&a=2&a=1", bytes.NewBufferString(`{"name":"test"}`))
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(auth.HeaderSdkDate, "20191115T033655Z")
+		f, err := auth.GetAPIGSignFunc("ak", "secret", "tok")
+		assert.NoError(t, err)
+		assert.NoError(t, f(r))
+		assert.Equal(t, "tok", r.Header.Get(auth.HeaderSecurityToken))
+		assert.Equal(t, "SDK-HMAC-SHA256 Access=ak, SignedHeaders=content-type;host;x-sdk-date;x-security-token, "+
+			"Signature=d466a590b49bdca157f243e5e3b1917ce098d967feeca26b583240c4943ccc33", r.Header.Get(auth.HeaderAuthorization))
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name":"test"}`, string(b), "body should still be readable")
+	})
+	t.Run("set sdk date if absent", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "https://example.com", nil)
+		assert.NoError(t, err)
+		f, err := auth.GetAPIGSignFunc("ak", "secret", "")
+		assert.NoError(t, err)
+		assert.NoError(t, f(r))
+		assert.NotEmpty(t, r.Header.Get(auth.HeaderSdkDate))
+		assert.Contains(t, r.Header.Get(auth.HeaderAuthorization), "SignedHeaders=host;x-sdk-date,")
+	})
+}
+
+func TestLoadAkskAuth_APIGSigner(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "aa", "as", "ap", "")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.signer", auth.SignerAPIG))
+	defer archaius.Delete("servicecomb.credentials.signer")
+	assert.NoError(t, auth.LoadAkskAuth())
+	r, err := http.NewRequest("GET", "https://example.com", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, httpclient.SignRequest(r))
+	assert.Contains(t, r.Header.Get(auth.HeaderAuthorization), "SDK-HMAC-SHA256 Access=aa,")
+
+	assert.NoError(t, archaius.Set("servicecomb.credentials.signer", "unknown"))
+	assert.Error(t, auth.LoadAkskAuth())
+}
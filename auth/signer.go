@@ -0,0 +1,140 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// Signer signs a request so that it can access huawei cloud,
+// a signer which may block, like refreshing a token, must return once ctx is done
+type Signer interface {
+	Sign(ctx context.Context, r *http.Request) error
+}
+
+// SignerFunc is an adapter to use a func as Signer
+type SignerFunc func(ctx context.Context, r *http.Request) error
+
+// Sign implements Signer
+func (f SignerFunc) Sign(ctx context.Context, r *http.Request) error {
+	return f(ctx, r)
+}
+
+// FromSignRequest adapts a SignRequest to Signer,
+// the sign func is not called if ctx is already done
+func FromSignRequest(f SignRequest) Signer {
+	return SignerFunc(func(ctx context.Context, r *http.Request) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return f(r)
+	})
+}
+
+// ToSignRequest adapts a Signer to SignRequest, so that it can be assigned to httpclient.SignRequest,
+// the context of request is passed to signer
+func ToSignRequest(s Signer) SignRequest {
+	return func(r *http.Request) error {
+		return s.Sign(r.Context(), r)
+	}
+}
+
+// CurrentSigner returns the signer loaded by LoadAuth, sign funcs read ctx as the context of request,
+// so a token signer stops waiting for refresh once ctx is done
+func CurrentSigner() Signer {
+	return SignerFunc(func(ctx context.Context, r *http.Request) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		req := r.WithContext(ctx)
+		err := signRequest(req)
+		// sign funcs set headers, and may replace a body they have read
+		r.Header, r.Body = req.Header, req.Body
+		return err
+	})
+}
+
+// Chain signs request with signers one by one, and stops at the first error
+func Chain(signers ...Signer) Signer {
+	return SignerFunc(func(ctx context.Context, r *http.Request) error {
+		for _, s := range signers {
+			if err := s.Sign(ctx, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// When signs request only if cond returns true
+func When(cond func(r *http.Request) bool, s Signer) Signer {
+	return SignerFunc(func(ctx context.Context, r *http.Request) error {
+		if !cond(r) {
+			return nil
+		}
+		return s.Sign(ctx, r)
+	})
+}
+
+// ForHost signs request only if it matches pattern, pattern is same as EndpointRule.Pattern
+func ForHost(pattern string, s Signer) Signer {
+	rule := &EndpointRule{Pattern: pattern}
+	return When(rule.match, s)
+}
+
+// SignInfo describes the credential which signed a request
+type SignInfo struct {
+	// Type is aksk, or the name of the cached token, like iam and rbac
+	Type string
+	// Provider is the credential provider of ak sk
+	Provider string
+	// Profile is the credential profile chosen by endpoint rules
+	Profile   string
+	AccessKey string
+	Project   string
+	ProjectID string
+}
+
+type signInfoKey struct{}
+
+// WithSignInfo returns a context to collect the sign info of a request,
+// the info is filled after the request is signed with this context
+func WithSignInfo(ctx context.Context) (context.Context, *SignInfo) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	info := &SignInfo{}
+	return context.WithValue(ctx, signInfoKey{}, info), info
+}
+
+// SignInfoFromContext returns the sign info collector saved by WithSignInfo
+func SignInfoFromContext(ctx context.Context) (*SignInfo, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	info, ok := ctx.Value(signInfoKey{}).(*SignInfo)
+	return info, ok
+}
+
+// recordSignInfo updates the sign info collector of request if there is one
+func recordSignInfo(r *http.Request, update func(info *SignInfo)) {
+	if info, ok := SignInfoFromContext(r.Context()); ok {
+		update(info)
+	}
+}
@@ -0,0 +1,126 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func testHeaderSigner(k, v string) auth.Signer {
+	return auth.FromSignRequest(func(r *http.Request) error {
+		r.Header.Set(k, v)
+		return nil
+	})
+}
+
+func TestSignerMiddleware(t *testing.T) {
+	s := auth.Chain(
+		testHeaderSigner("X-A", "a"),
+		auth.ForHost("*.example.com", testHeaderSigner("X-Host", "example")),
+		auth.When(func(r *http.Request) bool { return r.Method == http.MethodPost }, testHeaderSigner("X-Post", "post")),
+	)
+	r, _ := http.NewRequest(http.MethodGet, "https://cse.example.com:30100/v4", nil)
+	assert.NoError(t, auth.ToSignRequest(s)(r))
+	assert.Equal(t, "a", r.Header.Get("X-A"))
+	assert.Equal(t, "example", r.Header.Get("X-Host"))
+	assert.Empty(t, r.Header.Get("X-Post"))
+
+	r, _ = http.NewRequest(http.MethodPost, "https://127.0.0.1", nil)
+	assert.NoError(t, s.Sign(context.Background(), r))
+	assert.Empty(t, r.Header.Get("X-Host"))
+	assert.Equal(t, "post", r.Header.Get("X-Post"))
+
+	t.Log("chain stops at the first error")
+	errSign := errors.New("sign failed")
+	s = auth.Chain(auth.SignerFunc(func(context.Context, *http.Request) error { return errSign }), testHeaderSigner("X-A", "a"))
+	r, _ = http.NewRequest(http.MethodGet, "https://127.0.0.1", nil)
+	assert.Equal(t, errSign, s.Sign(context.Background(), r))
+	assert.Empty(t, r.Header.Get("X-A"))
+
+	t.Log("canceled context")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, testHeaderSigner("X-A", "a").Sign(ctx, r))
+}
+
+func TestTokenSigner(t *testing.T) {
+	release := make(chan struct{})
+	ct := auth.NewCachedToken("test", func() (*auth.Token, error) {
+		<-release
+		return &auth.Token{Value: "slow", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	defer ct.Stop()
+	s := auth.NewTokenSigner(ct, "X-Token", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r, _ := http.NewRequest(http.MethodGet, "https://127.0.0.1", nil)
+	assert.Equal(t, context.DeadlineExceeded, s.Sign(ctx, r), "do not wait for refresh after ctx is done")
+
+	close(release)
+	ctx, info := auth.WithSignInfo(context.Background())
+	r = r.WithContext(ctx)
+	assert.NoError(t, s.Sign(ctx, r))
+	assert.Equal(t, "slow", r.Header.Get("X-Token"))
+	assert.Equal(t, "test", info.Type)
+}
+
+func TestSignInfo(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "ia1", "is1", "ip1", "")
+	assert.NoError(t, auth.LoadAuth())
+
+	ctx, info := auth.WithSignInfo(context.Background())
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://127.0.0.1", nil)
+	assert.NoError(t, auth.CurrentSigner().Sign(ctx, r))
+	assert.Equal(t, auth.SignInfo{Type: auth.TypeAKSK, Provider: auth.ProviderFile, AccessKey: "ia1", Project: "ip1"}, *info)
+}
+
+func TestCurrentSigner_Context(t *testing.T) {
+	testInitEnv(t)
+	release := make(chan struct{})
+	var fetches int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			<-release
+		}
+		w.Header().Set(iam.HeaderSubjectToken, "ctx-token")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":{"expires_at":"` + time.Now().Add(200*time.Millisecond).UTC().Format(time.RFC3339Nano) + `"}}`))
+	}))
+	defer s.Close()
+	defer close(release)
+	for k, v := range map[string]string{
+		"servicecomb.credentials.type":         auth.TypeIAMToken,
+		"servicecomb.credentials.iam.endpoint": s.URL,
+		"servicecomb.credentials.iam.domain":   "domain",
+		"servicecomb.credentials.iam.user":     "user",
+		"servicecomb.credentials.iam.password": "pwd",
+		"servicecomb.credentials.project":      "cn-north-1",
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+	assert.NoError(t, auth.LoadAuth())
+	r, _ := http.NewRequest(http.MethodGet, "https://127.0.0.1", nil)
+	assert.NoError(t, auth.CurrentSigner().Sign(context.Background(), r))
+	assert.Equal(t, "ctx-token", r.Header.Get(auth.HeaderAuthToken))
+
+	time.Sleep(300 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	r, _ = http.NewRequest(http.MethodGet, "https://127.0.0.1", nil)
+	start := time.Now()
+	assert.Equal(t, context.Canceled, auth.CurrentSigner().Sign(ctx, r), "token wait stops once ctx is cancelled")
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Empty(t, r.Header.Get(auth.HeaderAuthToken))
+}
@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/openlog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultClockSkewThreshold is the skew tolerated before clock offset is adjusted
+const DefaultClockSkewThreshold = time.Minute
+
+const keyClockSkewThreshold = "servicecomb.credentials.clockSkewThreshold"
+
+var (
+	// clockOffset is added to local time when signing, in nanoseconds
+	clockOffset int64
+	// clockSkew is the last skew measured, in nanoseconds
+	clockSkew int64
+
+	clockSkewGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "huaweicloud_clock_skew_seconds",
+		Help: "server time minus local time, measured by Date header of responses",
+	})
+)
+
+func init() {
+	metrics.GetSystemPrometheusRegistry().MustRegister(clockSkewGauge)
+}
+
+// ClockOffset returns the offset added to local time when signing, it is adjusted when a request is rejected for a skewed clock
+func ClockOffset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&clockOffset))
+}
+
+// ClockSkew returns the last skew between server and local clock measured by responses of signed requests
+func ClockSkew() time.Duration {
+	return time.Duration(atomic.LoadInt64(&clockSkew))
+}
+
+// signingTime returns local time corrected by clock offset
+func signingTime() time.Time {
+	return time.Now().Add(ClockOffset())
+}
+
+// setSigningTime sets X-Sdk-Date if local clock is corrected, signers use it as the signing time
+func setSigningTime(r *http.Request) {
+	if ClockOffset() == 0 || r.Header.Get(HeaderSdkDate) != "" {
+		return
+	}
+	r.Header.Set(HeaderSdkDate, signingTime().UTC().Format(sdkDateFormat))
+}
+
+func clockSkewThreshold() time.Duration {
+	v := archaius.GetString(keyClockSkewThreshold, "")
+	if v == "" {
+		return DefaultClockSkewThreshold
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s [%s], use default value %s", keyClockSkewThreshold, v, DefaultClockSkewThreshold))
+		return DefaultClockSkewThreshold
+	}
+	return d
+}
+
+// measureClockSkew measures skew by Date header of response, it returns false if there is no Date header
+func measureClockSkew(resp *http.Response) (time.Duration, bool) {
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+	skew := serverTime.Sub(time.Now())
+	atomic.StoreInt64(&clockSkew, int64(skew))
+	clockSkewGauge.Set(skew.Seconds())
+	return skew, true
+}
+
+// adjustClockOffset corrects local clock by skew, if the corrected clock still differs from server more than threshold.
+// it returns false if clock is not the cause of rejection
+func adjustClockOffset(resp *http.Response) bool {
+	skew, ok := measureClockSkew(resp)
+	if !ok {
+		return false
+	}
+	diff := skew - ClockOffset()
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff < clockSkewThreshold() {
+		return false
+	}
+	atomic.StoreInt64(&clockOffset, int64(skew))
+	openlog.Warn(fmt.Sprintf("request to [%s] is rejected, local clock differs from server by %s, sign requests with this offset",
+		resp.Request.URL.Host, skew))
+	return true
+}
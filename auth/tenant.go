@@ -0,0 +1,73 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+type projectKey struct{}
+
+type profileKey struct{}
+
+// WithProject returns a context whose requests are signed for project instead of the configured one,
+// so that one process can call cloud services for many tenants.
+// it works with ak sk signers, a token signer fails with ErrTokenProjectMismatch as a token is scoped to its own project
+func WithProject(ctx context.Context, project string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+// ProjectFromContext returns the project saved by WithProject
+func ProjectFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	p, ok := ctx.Value(projectKey{}).(string)
+	return p, ok && p != ""
+}
+
+// WithProfile returns a context whose requests are signed with a credential profile of servicecomb.credentials.profiles,
+// it takes precedence over endpoint rules. signing fails if the profile does not exist,
+// a request is never signed with the credential of another tenant
+func WithProfile(ctx context.Context, profile string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, profileKey{}, profile)
+}
+
+// ProfileFromContext returns the profile saved by WithProfile
+func ProfileFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	p, ok := ctx.Value(profileKey{}).(string)
+	return p, ok && p != ""
+}
+
+// projectOf returns the project of request context, or the configured one
+func projectOf(r *http.Request, configured string) string {
+	if p, ok := ProjectFromContext(r.Context()); ok {
+		return p
+	}
+	return configured
+}
@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithProject(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "tenant-ak", "tenant-sk", "tp0", "")
+	for k, v := range map[string]interface{}{
+		"servicecomb.credentials.profiles.tenant1.accessKey": "tenant1-ak",
+		"servicecomb.credentials.profiles.tenant1.secretKey": "tenant1-sk",
+		"servicecomb.credentials.profiles.tenant1.project":   "tp1",
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+	assert.NoError(t, auth.LoadAuth())
+
+	signed := func(ctx context.Context) (*http.Request, *auth.SignInfo, error) {
+		ctx, info := auth.WithSignInfo(ctx)
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://cse.example.com", nil)
+		return r, info, httpclient.SignRequest(r)
+	}
+	r, info, err := signed(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-ak", r.Header.Get(auth.HeaderServiceAk))
+	assert.Equal(t, "tp0", r.Header.Get(auth.HeaderServiceProject))
+
+	t.Log("project of context")
+	r, info, err = signed(auth.WithProject(context.Background(), "tp9"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-ak", r.Header.Get(auth.HeaderServiceAk))
+	assert.Equal(t, "tp9", r.Header.Get(auth.HeaderServiceProject))
+	assert.Equal(t, "tp9", info.Project)
+
+	t.Log("profile of context")
+	r, info, err = signed(auth.WithProfile(context.Background(), "tenant1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant1-ak", r.Header.Get(auth.HeaderServiceAk))
+	assert.Equal(t, "tp1", r.Header.Get(auth.HeaderServiceProject))
+	assert.Equal(t, "tenant1", info.Profile)
+	r, _, err = signed(auth.WithProject(auth.WithProfile(context.Background(), "tenant1"), "tp1_sub"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant1-ak", r.Header.Get(auth.HeaderServiceAk))
+	assert.Equal(t, "tp1_sub", r.Header.Get(auth.HeaderServiceProject))
+
+	t.Log("never fall back to default credential if profile does not exist")
+	r, _, err = signed(auth.WithProfile(context.Background(), "tenant2"))
+	assert.True(t, errors.Is(err, auth.ErrProfileNotExist))
+	assert.Empty(t, r.Header.Get(auth.HeaderServiceAk))
+
+	t.Log("identity headers of other protocols")
+	h, err := auth.IdentityHeadersContext(auth.WithProject(context.Background(), "tp9"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-ak", h[auth.HeaderServiceAk])
+	assert.Equal(t, "tp9", h[auth.HeaderServiceProject])
+	h, err = auth.IdentityHeadersContext(auth.WithProfile(context.Background(), "tenant1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant1-ak", h[auth.HeaderServiceAk])
+	assert.Equal(t, "tp1", h[auth.HeaderServiceProject])
+	_, err = auth.IdentityHeadersContext(auth.WithProfile(context.Background(), "tenant2"))
+	assert.True(t, errors.Is(err, auth.ErrProfileNotExist))
+}
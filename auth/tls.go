@@ -0,0 +1,51 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/go-chassis/go-archaius"
+	chassistls "github.com/go-chassis/go-chassis/v2/core/tls"
+	"github.com/go-chassis/openlog"
+)
+
+const (
+	keyTLSCAFile             = "servicecomb.credentials.tls.caFile"
+	keyTLSInsecureSkipVerify = "servicecomb.credentials.tls.insecureSkipVerify"
+)
+
+// TLSConfig returns the TLS config of clients which send or receive credentials, like IAM, KMS and CSMS clients.
+// servers are verified by system CAs, or by servicecomb.credentials.tls.caFile if it is set.
+// verification is skipped only if servicecomb.credentials.tls.insecureSkipVerify is true
+func TLSConfig() (*tls.Config, error) {
+	if archaius.GetBool(keyTLSInsecureSkipVerify, false) {
+		openlog.Warn(keyTLSInsecureSkipVerify + " is true, servers receiving credentials are not verified")
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+	caFile := archaius.GetString(keyTLSCAFile, "")
+	if caFile == "" {
+		return &tls.Config{}, nil
+	}
+	pool, err := chassistls.GetX509CACertPool(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", keyTLSCAFile, err)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
@@ -0,0 +1,47 @@
+package auth_test
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSConfig(t *testing.T) {
+	s := httptest.NewTLSServer(http.NotFoundHandler())
+	defer s.Close()
+	get := func() error {
+		c, err := auth.TLSConfig()
+		if err != nil {
+			return err
+		}
+		resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: c}}).Get(s.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	assert.Error(t, get(), "server should be verified by default")
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, ioutil.WriteFile(caFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.tls.caFile", caFile))
+	defer archaius.Delete("servicecomb.credentials.tls.caFile")
+	assert.NoError(t, get())
+
+	assert.NoError(t, archaius.Set("servicecomb.credentials.tls.caFile", filepath.Join(t.TempDir(), "none.pem")))
+	_, err := auth.TLSConfig()
+	assert.Error(t, err)
+
+	assert.NoError(t, archaius.Set("servicecomb.credentials.tls.insecureSkipVerify", true))
+	defer archaius.Delete("servicecomb.credentials.tls.insecureSkipVerify")
+	assert.NoError(t, get(), "explicit opt out")
+}
@@ -0,0 +1,361 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/iam"
+	"github.com/go-chassis/openlog"
+)
+
+// credential types, set by servicecomb.credentials.type
+const (
+	TypeAKSK     = "aksk"
+	TypeIAMToken = "token"
+
+	HeaderAuthToken = "X-Auth-Token"
+
+	// IAMMethodPassword gets IAM token with user name and password
+	IAMMethodPassword = "password"
+	// IAMMethodAKSK gets IAM token with a SDK-HMAC-SHA256 signed request
+	IAMMethodAKSK = "aksk"
+
+	keyType        = "servicecomb.credentials.type"
+	keyIAMEndpoint = "servicecomb.credentials.iam.endpoint"
+	keyIAMMethod   = "servicecomb.credentials.iam.method"
+	keyIAMDomain   = "servicecomb.credentials.iam.domain"
+	keyIAMUser     = "servicecomb.credentials.iam.user"
+	keyIAMPassword = "servicecomb.credentials.iam.password"
+)
+
+var (
+	currentToken     *CachedToken
+	currentTokenLock sync.Mutex
+)
+
+// ErrTokenProjectMismatch means the project of request context is not the one the token is scoped to,
+// a token can not be used for another project
+var ErrTokenProjectMismatch = errors.New("token is not scoped to the project of context")
+
+// Token is a bearer token with expire time
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+	// Project is the project the token is scoped to
+	Project string
+}
+
+// TokenFetcher gets a new token from its issuer
+type TokenFetcher func() (*Token, error)
+
+// CachedToken caches a token until it is going to expire, and refreshes it in background
+type CachedToken struct {
+	name  string
+	fetch TokenFetcher
+	mu    sync.Mutex
+	token atomic.Value
+	// inflight is the refresh all callers wait for, there is at most one at a time
+	inflight *tokenRefresh
+	timer    *time.Timer
+	stopped  bool
+}
+
+// tokenRefresh is a refresh in flight, tk and err are set before done is closed
+type tokenRefresh struct {
+	done chan struct{}
+	tk   *Token
+	err  error
+}
+
+// NewCachedToken creates a cached token, name is used in logs
+func NewCachedToken(name string, fetch TokenFetcher) *CachedToken {
+	return &CachedToken{name: name, fetch: fetch}
+}
+
+// Token returns cached token, fetches a new one if it is expired
+func (t *CachedToken) Token() (*Token, error) {
+	return t.TokenContext(context.Background())
+}
+
+// TokenContext is same as Token, but it stops waiting for the new token once ctx is done,
+// the refresh keeps going on and the new token is cached for later callers.
+// concurrent callers share the same refresh
+func (t *CachedToken) TokenContext(ctx context.Context) (*Token, error) {
+	if tk, ok := t.token.Load().(*Token); ok && time.Now().Before(tk.ExpiresAt) {
+		return tk, nil
+	}
+	r := t.refresh(false)
+	select {
+	case <-r.done:
+		return r.tk, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Refresh fetches a new token, and schedules next refresh ahead of expiry.
+// if it fails, the cached token is kept
+func (t *CachedToken) Refresh() (*Token, error) {
+	r := t.refresh(true)
+	<-r.done
+	return r.tk, r.err
+}
+
+// refresh returns the refresh in flight, or starts a new one
+func (t *CachedToken) refresh(force bool) *tokenRefresh {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inflight != nil {
+		return t.inflight
+	}
+	r := &tokenRefresh{done: make(chan struct{})}
+	if tk, ok := t.token.Load().(*Token); ok && !force && time.Now().Before(tk.ExpiresAt) {
+		r.tk = tk
+		close(r.done)
+		return r
+	}
+	t.inflight = r
+	go t.doRefresh(r)
+	return r
+}
+
+func (t *CachedToken) doRefresh(r *tokenRefresh) {
+	tk, err := t.fetch()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer close(r.done)
+	t.inflight = nil
+	if err != nil {
+		emitCredentialEvent(CredentialEvent{Type: EventRefreshFailed, CredentialType: t.name, Error: err.Error()})
+		t.schedule(RefreshRetryInterval)
+		r.err = err
+		return
+	}
+	t.token.Store(tk)
+	d := time.Until(tk.ExpiresAt) - refreshAhead()
+	if d <= 0 {
+		d = RefreshRetryInterval
+	}
+	openlog.Debug(fmt.Sprintf("%s token expires at %s, refresh after %s", t.name, tk.ExpiresAt, d))
+	t.schedule(d)
+	r.tk = tk
+}
+
+func (t *CachedToken) schedule(d time.Duration) {
+	if t.stopped {
+		return
+	}
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.timer = time.AfterFunc(d, func() {
+		t.Refresh()
+	})
+}
+
+// Stop stops background refresh
+func (t *CachedToken) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// GetTokenSignFunc sets "prefix + token" to header
+func GetTokenSignFunc(t *CachedToken, header, prefix string) SignRequest {
+	return ToSignRequest(NewTokenSigner(t, header, prefix))
+}
+
+// NewTokenSigner sets "prefix + token" to header, it waits for token refresh until ctx is done.
+// signing fails with ErrTokenProjectMismatch if WithProject sets a project other than the one of token
+func NewTokenSigner(t *CachedToken, header, prefix string) Signer {
+	return SignerFunc(func(ctx context.Context, r *http.Request) error {
+		tk, err := t.TokenContext(ctx)
+		if err != nil {
+			return err
+		}
+		if p, ok := ProjectFromContext(ctx); ok && p != tk.Project {
+			return fmt.Errorf("%w: %s token of project [%s], project of context is [%s]",
+				ErrTokenProjectMismatch, t.name, tk.Project, p)
+		}
+		if r.Header == nil {
+			r.Header = make(http.Header)
+		}
+		r.Header.Set(header, prefix+tk.Value)
+		recordSignInfo(r, func(info *SignInfo) {
+			info.Type = t.name
+		})
+		return nil
+	})
+}
+
+// loadTokenAuth loads the auth of servicecomb.credentials.type
+func loadTokenAuth(credentialType string) error {
+	var err error
+	switch credentialType {
+	case TypeIAMToken:
+		err = LoadIAMTokenAuth()
+	case TypeRBAC:
+		err = loadRBACAuthWithAKSK()
+	default:
+		err = fmt.Errorf("unknown credential type [%s]", credentialType)
+	}
+	if err != nil {
+		openlog.Error(fmt.Sprintf("load %s auth failed: %s", credentialType, Redact(err.Error())))
+		return err
+	}
+	openlog.Info(fmt.Sprintf("huawei cloud %s auth enabled", credentialType))
+	return nil
+}
+
+// setTokenAuth fetches the first token, and signs requests with sign func of the token
+func setTokenAuth(t *CachedToken, sign SignRequest) error {
+	if _, err := t.Refresh(); err != nil {
+		t.Stop()
+		return err
+	}
+	currentTokenLock.Lock()
+	defer currentTokenLock.Unlock()
+	if currentToken != nil {
+		currentToken.Stop()
+	}
+	currentToken = t
+	setSignRequest(sign)
+	installSignRequest()
+	emitCredentialEvent(CredentialEvent{Type: EventLoaded, CredentialType: t.name})
+	return nil
+}
+
+func tokenAuthEnabled() bool {
+	currentTokenLock.Lock()
+	defer currentTokenLock.Unlock()
+	return currentToken != nil
+}
+
+func stopTokenAuth() {
+	currentTokenLock.Lock()
+	defer currentTokenLock.Unlock()
+	if currentToken != nil {
+		currentToken.Stop()
+		currentToken = nil
+	}
+}
+
+// LoadIAMTokenAuth gets IAM token with password or ak/sk, and injects X-Auth-Token to requests
+func LoadIAMTokenAuth() error {
+	fetch, err := newIAMTokenFetcher()
+	if err != nil {
+		return err
+	}
+	t := NewCachedToken("iam", fetch)
+	return setTokenAuth(t, GetTokenSignFunc(t, HeaderAuthToken, ""))
+}
+
+func newIAMTokenFetcher() (TokenFetcher, error) {
+	endpoint := archaius.GetString(keyIAMEndpoint, "")
+	if endpoint == "" {
+		return nil, errors.New(keyIAMEndpoint + " is empty")
+	}
+	switch method := archaius.GetString(keyIAMMethod, IAMMethodPassword); method {
+	case IAMMethodPassword:
+		return newIAMPasswordTokenFetcher(endpoint)
+	case IAMMethodAKSK:
+		return newIAMAKSKTokenFetcher(endpoint), nil
+	default:
+		return nil, fmt.Errorf("unknown iam method [%s]", method)
+	}
+}
+
+// newIAMClient creates IAM client verifying server by TLSConfig
+func newIAMClient(endpoint string, sign SignRequest) (*iam.Client, error) {
+	tlsConfig, err := TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return iam.New(iam.Options{Endpoint: endpoint, Signer: sign, TLSConfig: tlsConfig})
+}
+
+func newIAMPasswordTokenFetcher(endpoint string) (TokenFetcher, error) {
+	domain := archaius.GetString(keyIAMDomain, "")
+	user := archaius.GetString(keyIAMUser, "")
+	if domain == "" || user == "" {
+		return nil, errors.New("iam domain or user is empty")
+	}
+	// password can be encrypted just like sk, user is masked as ak
+	pwd, err := decryptSecretKey(scopeIAMPassword, &Credential{
+		AccessKey:        user,
+		SecretKey:        archaius.GetString(keyIAMPassword, ""),
+		AkskCustomCipher: serviceCipher(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	project, err := resolveProject(archaius.GetString(keyProjectV2, archaius.GetString(keyProject, "")))
+	if err != nil {
+		return nil, err
+	}
+	c, err := newIAMClient(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	return func() (*Token, error) {
+		t, err := c.GetPasswordToken(domain, user, pwd, project)
+		if err != nil {
+			return nil, err
+		}
+		return &Token{Value: t.Value, ExpiresAt: t.ExpiresAt, Project: project}, nil
+	}, nil
+}
+
+// newIAMAKSKTokenFetcher reads ak/sk from provider chain each time,
+// so that the rotated ak/sk is used
+func newIAMAKSKTokenFetcher(endpoint string) TokenFetcher {
+	return func() (*Token, error) {
+		cred, _, err := getAkskConfig()
+		if err != nil {
+			return nil, err
+		}
+		sk, err := decryptSecretKey(scopeIAMAKSK, cred)
+		if err != nil {
+			return nil, err
+		}
+		sign, err := GetAPIGSignFunc(cred.AccessKey, sk, cred.SecurityToken)
+		if err != nil {
+			return nil, err
+		}
+		c, err := newIAMClient(endpoint, sign)
+		if err != nil {
+			return nil, err
+		}
+		t, err := c.GetAKSKToken(cred.AccessKey, cred.Project)
+		if err != nil {
+			return nil, err
+		}
+		return &Token{Value: t.Value, ExpiresAt: t.ExpiresAt, Project: cred.Project}, nil
+	}
+}
@@ -0,0 +1,120 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedToken(t *testing.T) {
+	var mu sync.Mutex
+	n := 0
+	var fail bool
+	ct := auth.NewCachedToken("test", func() (*auth.Token, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return nil, errors.New("issuer is down")
+		}
+		n++
+		return &auth.Token{Value: string(rune('a' + n)), ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	defer ct.Stop()
+
+	tk, err := ct.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "b", tk.Value)
+	tk, err = ct.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "b", tk.Value, "cached")
+
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	_, err = ct.Refresh()
+	assert.Error(t, err)
+	tk, err = ct.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "b", tk.Value, "keep the cached token if refresh failed")
+}
+
+func TestCachedToken_SharedRefresh(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	ct := auth.NewCachedToken("test", func() (*auth.Token, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return &auth.Token{Value: "shared", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	defer ct.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ct.TokenContext(ctx)
+	assert.Equal(t, context.Canceled, err, "do not wait after ctx is done")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tk, err := ct.TokenContext(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "shared", tk.Value)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "callers wait for the same refresh")
+}
+
+func TestLoadAuth_IAMToken(t *testing.T) {
+	testInitEnv(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(iam.HeaderSubjectToken, "iam-token")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":{"expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}}`))
+	}))
+	defer s.Close()
+	for k, v := range map[string]string{
+		"servicecomb.credentials.type":         auth.TypeIAMToken,
+		"servicecomb.credentials.iam.endpoint": s.URL,
+		"servicecomb.credentials.iam.domain":   "domain",
+		"servicecomb.credentials.iam.user":     "user",
+		"servicecomb.credentials.iam.password": "pwd",
+		"servicecomb.credentials.project":      "cn-north-1",
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+		defer archaius.Delete(k)
+	}
+
+	assert.NoError(t, auth.LoadAuth())
+	r, _ := http.NewRequest("GET", "http://127.0.0.1", nil)
+	assert.NoError(t, httpclient.SignRequest(r))
+	assert.Equal(t, "iam-token", r.Header.Get(auth.HeaderAuthToken))
+
+	t.Log("token is scoped to configured project")
+	r, _ = http.NewRequestWithContext(auth.WithProject(context.Background(), "cn-north-1"), "GET", "http://127.0.0.1", nil)
+	assert.NoError(t, httpclient.SignRequest(r))
+	assert.Equal(t, "iam-token", r.Header.Get(auth.HeaderAuthToken))
+	r, _ = http.NewRequestWithContext(auth.WithProject(context.Background(), "cn-south-1"), "GET", "http://127.0.0.1", nil)
+	err := httpclient.SignRequest(r)
+	assert.True(t, errors.Is(err, auth.ErrTokenProjectMismatch), err)
+	assert.Empty(t, r.Header.Get(auth.HeaderAuthToken))
+
+	assert.NoError(t, archaius.Set("servicecomb.credentials.iam.method", "unknown"))
+	defer archaius.Delete("servicecomb.credentials.iam.method")
+	assert.Error(t, auth.LoadAuth())
+}
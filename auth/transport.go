@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+)
+
+// SigningTransport signs requests before sending them, it is an http.RoundTripper for clients not using httpclient.
+// if a request is rejected with 401 or 403, and Date of response shows local clock is skewed,
+// the clock offset is adjusted, and the request is signed again and retried once.
+// if the clock is fine, the request is signed with ak sk loaded by LoadAuth, and the ak is rejected,
+// which is a 401, or a 403 with an error code of APIG telling the ak or signature is wrong,
+// the secondary credential is promoted and the request is retried once.
+// a 403 for missing permission leaves the credential as it is.
+// clients in pkg/client retry the same way, they are created by resign.New
+type SigningTransport struct {
+	// Base sends requests, it is http.DefaultTransport if nil
+	Base http.RoundTripper
+	// Signer signs requests, it is CurrentSigner() if nil
+	Signer Signer
+}
+
+// NewSigningTransport returns a transport signing requests with signer
+func NewSigningTransport(base http.RoundTripper, signer Signer) *SigningTransport {
+	return &SigningTransport{Base: base, Signer: signer}
+}
+
+// RoundTrip implements http.RoundTripper, r is not modified, a signed copy of it is sent
+func (t *SigningTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if _, ok := SignInfoFromContext(r.Context()); !ok {
+		// sign info tells which ak is rejected
+		ctx, _ := WithSignInfo(r.Context())
+		r = r.WithContext(ctx)
+	}
+	signer := t.signer()
+	rt := &resign.Transport{Base: t.Base, Sign: func(req *http.Request) error {
+		return signer.Sign(req.Context(), req)
+	}}
+	return rt.RoundTrip(r)
+}
+
+func (t *SigningTransport) signer() Signer {
+	if t.Signer == nil {
+		return CurrentSigner()
+	}
+	return t.Signer
+}
+
+// retryRejected retries a request rejected because of the clock offset or the primary ak,
+// after the offset is adjusted or the secondary credential is promoted
+func retryRejected(resp *http.Response) bool {
+	measureClockSkew(resp)
+	return isAuthRejected(resp) && (adjustClockOffset(resp) ||
+		isCredentialRejected(resp) && promoteSecondary(signedAccessKey(resp.Request)))
+}
+
+func isAuthRejected(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden
+}
+
+// apigCredentialErrors are error codes of APIG for a wrong ak or signature
+var apigCredentialErrors = map[string]bool{
+	"APIGW.0301": true, // incorrect IAM authentication information
+	"APIGW.0303": true, // incorrect app authentication information
+}
+
+// isCredentialRejected tells if the ak or signature of request is rejected, so that another ak may be accepted,
+// a 403 is not, unless APIG says the ak or signature is wrong
+func isCredentialRejected(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	return apigCredentialErrors[peekErrorCode(resp)]
+}
+
+// peekErrorCode reads error_code of APIG in response body, the body is restored for the caller
+func peekErrorCode(resp *http.Response) string {
+	if resp.Body == nil {
+		return ""
+	}
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+	e := &struct {
+		Code  string `json:"error_code"`
+		Error struct {
+			Code string `json:"error_code"`
+		} `json:"error"`
+	}{}
+	if json.Unmarshal(b, e) != nil {
+		return ""
+	}
+	if e.Code != "" {
+		return e.Code
+	}
+	return e.Error.Code
+}
+
+func init() {
+	resign.InstallRetrier(retryRejected)
+}
@@ -0,0 +1,102 @@
+package auth_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSkewedServer rejects requests whose X-Sdk-Date differs from its clock more than 5 minutes
+func newSkewedServer(skew *int64, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		now := time.Now().Add(time.Duration(atomic.LoadInt64(skew)))
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+		t, err := time.Parse("20060102T150405Z", r.Header.Get(auth.HeaderSdkDate))
+		if err != nil || t.Sub(now) > 5*time.Minute || now.Sub(t) > 5*time.Minute {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+}
+
+func TestSigningTransport_RoundTrip(t *testing.T) {
+	skew := int64(time.Hour)
+	var hits int32
+	s := newSkewedServer(&skew, &hits)
+	defer s.Close()
+	sign, err := auth.GetAPIGSignFunc("ak", "sk", "")
+	assert.NoError(t, err)
+	c := &http.Client{Transport: auth.NewSigningTransport(nil, auth.FromSignRequest(sign))}
+
+	post := func() (int, string) {
+		resp, err := c.Post(s.URL, "text/plain", strings.NewReader("hello"))
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	t.Run("adjust clock offset and retry once", func(t *testing.T) {
+		code, body := post()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "hello", body, "body should be sent again")
+		assert.Equal(t, int32(2), atomic.SwapInt32(&hits, 0))
+		assert.InDelta(t, time.Hour.Seconds(), auth.ClockOffset().Seconds(), 2)
+		assert.InDelta(t, time.Hour.Seconds(), auth.ClockSkew().Seconds(), 2)
+	})
+	t.Run("sign with offset", func(t *testing.T) {
+		code, _ := post()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int32(1), atomic.SwapInt32(&hits, 0))
+	})
+	t.Run("adjust offset back after clock is fixed", func(t *testing.T) {
+		atomic.StoreInt64(&skew, 0)
+		code, _ := post()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int32(2), atomic.SwapInt32(&hits, 0))
+		assert.InDelta(t, 0, auth.ClockOffset().Seconds(), 2)
+	})
+	t.Run("do not retry rejection not caused by clock", func(t *testing.T) {
+		sign, err := auth.GetShaAKSKSignFunc("ak", "sk", "")
+		assert.NoError(t, err)
+		c := &http.Client{Transport: auth.NewSigningTransport(nil, auth.FromSignRequest(sign))}
+		resp, err := c.Get(s.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.SwapInt32(&hits, 0))
+	})
+}
+
+func TestClientsRetrySkewedClock(t *testing.T) {
+	skew := int64(2 * time.Hour)
+	var hits int32
+	s := newSkewedServer(&skew, &hits)
+	defer s.Close()
+	sign, err := auth.GetAPIGSignFunc("ak", "sk", "")
+	assert.NoError(t, err)
+	c, err := resign.New(&httpclient.Options{SignRequest: sign})
+	assert.NoError(t, err)
+
+	resp, err := c.Post(context.Background(), s.URL, nil, []byte("hello"))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", string(b))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+	assert.InDelta(t, (2 * time.Hour).Seconds(), auth.ClockOffset().Seconds(), 2)
+}
@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/iam"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/openlog"
+)
+
+const (
+	keyValidate       = "servicecomb.credentials.validate"
+	keyValidateTarget = "servicecomb.credentials.validateTarget"
+)
+
+// targets of servicecomb.credentials.validateTarget, IAM by default
+const (
+	// ValidateTargetIAM validates credential by looking up its project in IAM
+	ValidateTargetIAM = "iam"
+	// ValidateTargetEngineManager validates credential by a ShaAKSK signed request to CSE engine manager,
+	// it works on premises where there is no IAM, import package provider/huawei/engine to install it
+	ValidateTargetEngineManager = "engineManager"
+)
+
+// errors of credential validation, they tell why the credential does not work
+var (
+	ErrAccessKeyNotExist      = errors.New("access key does not exist")
+	ErrSecretKeyMismatch      = errors.New("secret key does not match access key")
+	ErrProjectNotExist        = errors.New("project does not exist")
+	ErrCredentialRejected     = errors.New("credential is rejected")
+	ErrValidationNetwork      = errors.New("can not reach the cloud service to validate credential")
+	errNoCredentialToValidate = fmt.Errorf("%s is true, but %w", keyValidate, ErrAuthConfNotExist)
+)
+
+// CredentialValidator checks credential c with its decrypted sk by a signed request to a cloud service.
+// it returns an error wrapping ErrCredentialRejected if the service rejects the credential
+type CredentialValidator func(c *Credential, sk string) error
+
+var (
+	validators     = map[string]CredentialValidator{ValidateTargetIAM: validateByIAM}
+	validatorsLock sync.RWMutex
+)
+
+// InstallCredentialValidator installs a validator of servicecomb.credentials.validateTarget
+func InstallCredentialValidator(target string, v CredentialValidator) {
+	validatorsLock.Lock()
+	defer validatorsLock.Unlock()
+	validators[target] = v
+}
+
+func validationTarget() string {
+	return archaius.GetString(keyValidateTarget, ValidateTargetIAM)
+}
+
+// ValidateCredential checks the credential of provider chain by the validator of servicecomb.credentials.validateTarget.
+// the error wraps one of ErrAccessKeyNotExist, ErrSecretKeyMismatch, ErrProjectNotExist,
+// ErrCredentialRejected and ErrValidationNetwork, or it is returned by the cloud service as it is
+func ValidateCredential() error {
+	c, _, err := getAkskConfig()
+	if err != nil {
+		return err
+	}
+	sk, err := decryptSecretKey(scopeValidate, c)
+	if err != nil {
+		return err
+	}
+	return validateCredential(c, sk)
+}
+
+// validateCredential checks c with decrypted sk, and tells why it does not work
+func validateCredential(c *Credential, sk string) error {
+	target := validationTarget()
+	validatorsLock.RLock()
+	v, ok := validators[target]
+	validatorsLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("no credential validator of %s [%s]", keyValidateTarget, target)
+	}
+	err := v(c, sk)
+	if err == nil {
+		return nil
+	}
+	var ue *url.Error
+	switch {
+	case errors.As(err, &ue):
+		return fmt.Errorf("%w of %s: %v", ErrValidationNetwork, target, err)
+	case errors.Is(err, ErrCredentialRejected):
+		if r := credentialRejection(err); r != ErrCredentialRejected {
+			return fmt.Errorf("%w: %v", r, err)
+		}
+		return err
+	default:
+		return err
+	}
+}
+
+// validateByIAM looks up the project of c in IAM with a request signed by APIG signer
+func validateByIAM(c *Credential, sk string) error {
+	sign, err := GetAPIGSignFunc(c.AccessKey, sk, c.SecurityToken)
+	if err != nil {
+		return err
+	}
+	endpoint := iamEndpoint(c.Project)
+	client, err := newIAMClient(endpoint, sign)
+	if err != nil {
+		return err
+	}
+	_, err = client.GetProjectID(c.Project)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, iam.ErrUnauthorized):
+		return fmt.Errorf("%w: ak %s, %v", ErrCredentialRejected, MaskSecret(c.AccessKey), err)
+	case errors.Is(err, iam.ErrProjectNotFound):
+		if c.Project == common.DefaultValue {
+			// no project is configured, IAM has accepted ak sk
+			return nil
+		}
+		return fmt.Errorf("%w: [%s] is not a project of ak %s", ErrProjectNotExist, c.Project, MaskSecret(c.AccessKey))
+	default:
+		return err
+	}
+}
+
+// isRejection returns true if err tells the credential is rejected, rather than it can not be validated
+func isRejection(err error) bool {
+	return errors.Is(err, ErrAccessKeyNotExist) || errors.Is(err, ErrSecretKeyMismatch) || errors.Is(err, ErrCredentialRejected)
+}
+
+// credentialRejection tells which key is wrong by the message of APIG, like
+// "Incorrect IAM authentication information: ak xxx not exist",
+// "Incorrect IAM authentication information: verify aksk signature fail"
+func credentialRejection(err error) error {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "not exist"):
+		return ErrAccessKeyNotExist
+	case strings.Contains(msg, "signature"):
+		return ErrSecretKeyMismatch
+	default:
+		return ErrCredentialRejected
+	}
+}
+
+// validateOnStartup validates credential a loaded with err if servicecomb.credentials.validate is true,
+// no credential fails the validation. err is returned as it is if validation is disabled
+func validateOnStartup(a *akskAuth, err error) error {
+	if !archaius.GetBool(keyValidate, false) {
+		return err
+	}
+	if err == ErrAuthConfNotExist {
+		err = errNoCredentialToValidate
+	}
+	if err == nil {
+		err = a.validate()
+	}
+	if err != nil {
+		return fmt.Errorf("credential validation failed: %w", err)
+	}
+	openlog.Info("credential is validated by " + validationTarget())
+	return nil
+}
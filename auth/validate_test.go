@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCredential(t *testing.T) {
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		authorization := r.Header.Get(auth.HeaderAuthorization)
+		switch {
+		case strings.Contains(authorization, "Access=vak-unknown,"):
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error_msg":"Incorrect IAM authentication information: ak vak-unknown not exist","error_code":"APIGW.0301"}`))
+		case strings.Contains(authorization, "Access=vak-wrongsk,"):
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error_msg":"Incorrect IAM authentication information: verify aksk signature fail","error_code":"APIGW.0301"}`))
+		case strings.Contains(authorization, "Access=vak-locked,"):
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error_msg":"forbidden","error_code":"APIGW.0302"}`))
+		case r.URL.Query().Get("name") == "cn-north-4_vp":
+			w.Write([]byte(`{"projects":[{"id":"vpid","name":"cn-north-4_vp"}]}`))
+		default:
+			w.Write([]byte(`{"projects":[]}`))
+		}
+	}))
+	defer s.Close()
+	credentialFilePath := testInitEnv(t)
+	assert.NoError(t, archaius.Set("servicecomb.credentials.iam.endpoint", s.URL))
+	defer archaius.Delete("servicecomb.credentials.iam.endpoint")
+
+	for name, c := range map[string]struct {
+		ak, project string
+		err         error
+	}{
+		"valid":             {"vak", "cn-north-4_vp", nil},
+		"ak not exist":      {"vak-unknown", "cn-north-4_vp", auth.ErrAccessKeyNotExist},
+		"sk mismatch":       {"vak-wrongsk", "cn-north-4_vp", auth.ErrSecretKeyMismatch},
+		"rejected":          {"vak-locked", "cn-north-4_vp", auth.ErrCredentialRejected},
+		"project not exist": {"vak", "cn-north-4_none", auth.ErrProjectNotExist},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testWriteFile(t, credentialFilePath, c.ak, "vsk", c.project, "")
+			err := auth.ValidateCredential()
+			if c.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, c.err), err)
+			assert.NotContains(t, err.Error(), "vsk")
+		})
+	}
+	t.Run("network", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		assert.NoError(t, archaius.Set("servicecomb.credentials.iam.endpoint", closed.URL))
+		defer archaius.Set("servicecomb.credentials.iam.endpoint", s.URL)
+		testWriteFile(t, credentialFilePath, "vak", "vsk", "cn-north-4_vp", "")
+		assert.True(t, errors.Is(auth.ValidateCredential(), auth.ErrValidationNetwork))
+	})
+	t.Run("load auth fails on startup", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("servicecomb.credentials.validate", true))
+		defer archaius.Delete("servicecomb.credentials.validate")
+		testWriteFile(t, credentialFilePath, "vak-wrongsk", "vsk", "cn-north-4_vp", "")
+		err := auth.LoadAuth()
+		assert.True(t, errors.Is(err, auth.ErrSecretKeyMismatch))
+		assert.Contains(t, err.Error(), "credential validation failed")
+	})
+	t.Run("load auth validates the loaded credential once", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("servicecomb.credentials.validate", true))
+		defer archaius.Delete("servicecomb.credentials.validate")
+		testWriteFile(t, credentialFilePath, "vak", "vsk", "cn-north-4_vp", "")
+		atomic.StoreInt32(&requests, 0)
+		assert.NoError(t, auth.LoadAuth())
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+		assert.Equal(t, "vak", testSignedAk(t))
+	})
+	t.Run("validate by engine manager", func(t *testing.T) {
+		var validated string
+		auth.InstallCredentialValidator(auth.ValidateTargetEngineManager, func(c *auth.Credential, sk string) error {
+			validated = c.AccessKey + "/" + sk
+			if c.AccessKey == "vak-unknown" {
+				return fmt.Errorf("%w: ak vak-unknown not exist", auth.ErrCredentialRejected)
+			}
+			return nil
+		})
+		assert.NoError(t, archaius.Set("servicecomb.credentials.validateTarget", auth.ValidateTargetEngineManager))
+		defer archaius.Delete("servicecomb.credentials.validateTarget")
+		testWriteFile(t, credentialFilePath, "vak", "vsk", "cn-north-4_vp", "")
+		atomic.StoreInt32(&requests, 0)
+		assert.NoError(t, auth.ValidateCredential())
+		assert.Equal(t, "vak/vsk", validated)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&requests), "IAM is not asked")
+
+		testWriteFile(t, credentialFilePath, "vak-unknown", "vsk", "cn-north-4_vp", "")
+		assert.True(t, errors.Is(auth.ValidateCredential(), auth.ErrAccessKeyNotExist))
+
+		assert.NoError(t, archaius.Set("servicecomb.credentials.validateTarget", "unknown"))
+		assert.Error(t, auth.ValidateCredential())
+	})
+}
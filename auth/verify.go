@@ -0,0 +1,221 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-chassis/go-archaius"
+	"gopkg.in/yaml.v2"
+)
+
+const keyTrusted = "servicecomb.credentials.trusted"
+
+// errors of ShaAKSK verification
+var (
+	ErrNoAKSKHeader     = errors.New("ak or ShaAKSK header is missing")
+	ErrUntrustedAK      = errors.New("ak is not trusted")
+	ErrInvalidShaAKSK   = errors.New("ShaAKSK is invalid")
+	ErrProjectMismatch  = errors.New("project is not allowed for this ak")
+	ErrNoTrustedAKSK    = errors.New("no trusted ak sk")
+	errEmptyTrustedAKSK = errors.New("trusted ak or sk is empty")
+)
+
+var currentVerifier atomic.Value
+
+// Identity is the caller authenticated by ShaAKSK headers
+type Identity struct {
+	AccessKey string
+	Project   string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the authenticated caller
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the authenticated caller saved by WithIdentity
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+type trustedAKSK struct {
+	shaAKSK string
+	project string
+}
+
+// Verifier verifies X-Service-AK, X-Service-ShaAKSK and X-Service-Project headers
+// produced by GetShaAKSKSignFunc against trusted credentials
+type Verifier struct {
+	trusted map[string]*trustedAKSK
+}
+
+// NewVerifier decrypts sk of trusted credentials with their akskCustomCipher,
+// a credential without project is trusted in any project
+func NewVerifier(credentials ...*Credential) (*Verifier, error) {
+	v := &Verifier{trusted: make(map[string]*trustedAKSK, len(credentials))}
+	for i, c := range credentials {
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return nil, errEmptyTrustedAKSK
+		}
+		sk, err := decryptSecretKey(fmt.Sprintf("trusted.%d", i), c)
+		if err != nil {
+			return nil, err
+		}
+		shaAKSK, err := genShaAKSK(sk, c.AccessKey)
+		if err != nil {
+			return nil, err
+		}
+		v.trusted[c.AccessKey] = &trustedAKSK{shaAKSK: shaAKSK, project: c.Project}
+	}
+	return v, nil
+}
+
+// Verify returns the identity if ak is trusted and ShaAKSK matches
+func (v *Verifier) Verify(ak, shaAKSK, project string) (*Identity, error) {
+	if ak == "" || shaAKSK == "" {
+		return nil, ErrNoAKSKHeader
+	}
+	t, ok := v.trusted[ak]
+	if !ok {
+		return nil, ErrUntrustedAK
+	}
+	if !hmac.Equal([]byte(t.shaAKSK), []byte(shaAKSK)) {
+		return nil, ErrInvalidShaAKSK
+	}
+	if t.project != "" && t.project != project {
+		return nil, ErrProjectMismatch
+	}
+	return &Identity{AccessKey: ak, Project: project}, nil
+}
+
+// VerifyHeaders is same as Verify, it reads headers case-insensitively,
+// so that it works with invocation headers of any protocol
+func (v *Verifier) VerifyHeaders(headers map[string]string) (*Identity, error) {
+	return v.Verify(headerValue(headers, HeaderServiceAk),
+		headerValue(headers, HeaderServiceShaAKSK),
+		headerValue(headers, HeaderServiceProject))
+}
+
+func headerValue(headers map[string]string, key string) string {
+	if v, ok := headers[key]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// GetVerifier returns the verifier of current trusted credentials,
+// it is loaded again after credentials change
+func GetVerifier() (*Verifier, error) {
+	if v, ok := currentVerifier.Load().(*Verifier); ok && v != nil {
+		return v, nil
+	}
+	v, err := LoadVerifier()
+	if err != nil {
+		return nil, err
+	}
+	currentVerifier.Store(v)
+	return v, nil
+}
+
+func resetVerifier() {
+	currentVerifier.Store((*Verifier)(nil))
+}
+
+// LoadVerifier trusts the credential of this service itself,
+// and credentials in servicecomb.credentials.trusted
+func LoadVerifier() (*Verifier, error) {
+	trusted, err := getTrustedConfig()
+	if err != nil {
+		return nil, err
+	}
+	c, _, err := getAkskConfig()
+	if err != nil && err != ErrAuthConfNotExist {
+		return nil, err
+	}
+	if err == nil {
+		c.Project = ""
+		trusted = append(trusted, c)
+		// both keys are valid during rotation, callers may switch to the new one earlier than this service
+		if c.Secondary != nil && c.Secondary.AccessKey != "" {
+			trusted = append(trusted, c.secondaryCredential())
+		}
+	}
+	if len(trusted) == 0 {
+		return nil, ErrNoTrustedAKSK
+	}
+	return NewVerifier(trusted...)
+}
+
+// getTrustedConfig reads servicecomb.credentials.trusted in both ${CIPHER_ROOT}/certificate.yaml and chassis config
+func getTrustedConfig() ([]*Credential, error) {
+	trusted := make([]*Credential, 0)
+	if v, exist := os.LookupEnv(CipherRootEnv); exist {
+		yamlContent, err := ioutil.ReadFile(filepath.Join(v, KeytoolAkskFile))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			conf := &struct {
+				ServiceComb struct {
+					Credentials struct {
+						Trusted []*Credential `yaml:"trusted"`
+					} `yaml:"credentials"`
+				} `yaml:"servicecomb"`
+			}{}
+			if err := yaml.Unmarshal(yamlContent, conf); err != nil {
+				return nil, err
+			}
+			trusted = append(trusted, conf.ServiceComb.Credentials.Trusted...)
+		}
+	}
+	if v := archaius.Get(keyTrusted); v != nil {
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*Credential, 0)
+		if err := yaml.Unmarshal(b, &list); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", keyTrusted, err)
+		}
+		trusted = append(trusted, list...)
+	}
+	return trusted, nil
+}
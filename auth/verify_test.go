@@ -0,0 +1,82 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func testShaAKSKHeaders(t *testing.T, ak, sk, project string) map[string]string {
+	f, err := auth.GetShaAKSKSignFunc(ak, sk, project)
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	assert.NoError(t, f(r))
+	m := make(map[string]string)
+	for k := range r.Header {
+		m[k] = r.Header.Get(k)
+	}
+	return m
+}
+
+func TestVerifier_VerifyHeaders(t *testing.T) {
+	v, err := auth.NewVerifier(&auth.Credential{AccessKey: "ak1", SecretKey: "sk1"},
+		&auth.Credential{AccessKey: "ak2", SecretKey: "sk2", Project: "p2"})
+	assert.NoError(t, err)
+
+	id, err := v.VerifyHeaders(testShaAKSKHeaders(t, "ak1", "sk1", "any"))
+	assert.NoError(t, err)
+	assert.Equal(t, &auth.Identity{AccessKey: "ak1", Project: "any"}, id)
+
+	id, err = v.VerifyHeaders(map[string]string{
+		"x-service-ak":      "ak2",
+		"x-service-shaaksk": testShaAKSKHeaders(t, "ak2", "sk2", "p2")[http.CanonicalHeaderKey(auth.HeaderServiceShaAKSK)],
+		"x-service-project": "p2",
+	})
+	assert.NoError(t, err, "header names are case insensitive")
+	assert.Equal(t, "p2", id.Project)
+
+	_, err = v.VerifyHeaders(map[string]string{})
+	assert.Equal(t, auth.ErrNoAKSKHeader, err)
+	_, err = v.VerifyHeaders(testShaAKSKHeaders(t, "ak3", "sk3", "p3"))
+	assert.Equal(t, auth.ErrUntrustedAK, err)
+	_, err = v.VerifyHeaders(testShaAKSKHeaders(t, "ak1", "wrong", "p1"))
+	assert.Equal(t, auth.ErrInvalidShaAKSK, err)
+	_, err = v.VerifyHeaders(testShaAKSKHeaders(t, "ak2", "sk2", "p1"))
+	assert.Equal(t, auth.ErrProjectMismatch, err)
+
+	_, err = auth.NewVerifier(&auth.Credential{AccessKey: "ak1"})
+	assert.Error(t, err)
+}
+
+func TestLoadVerifier(t *testing.T) {
+	credentialFilePath := testInitEnv(t)
+	testWriteFile(t, credentialFilePath, "va", "vs", "vp", "")
+	_, err := auth.LoadVerifier()
+	assert.NoError(t, err)
+
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "archaius"))
+	defer archaius.Delete("servicecomb.credentials.providers")
+	assert.NoError(t, archaius.Set("servicecomb.credentials.trusted", []interface{}{
+		map[interface{}]interface{}{"accessKey": "ta", "secretKey": "ts", "project": "tp"},
+	}))
+	defer archaius.Delete("servicecomb.credentials.trusted")
+	v, err := auth.LoadVerifier()
+	assert.NoError(t, err)
+	_, err = v.VerifyHeaders(testShaAKSKHeaders(t, "ta", "ts", "tp"))
+	assert.NoError(t, err)
+	_, err = v.VerifyHeaders(testShaAKSKHeaders(t, "va", "vs", "vp"))
+	assert.Equal(t, auth.ErrUntrustedAK, err, "credential file is not in provider chain")
+}
+
+func TestIdentityFromContext(t *testing.T) {
+	_, ok := auth.IdentityFromContext(context.Background())
+	assert.False(t, ok)
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{AccessKey: "ak", Project: "p"})
+	id, ok := auth.IdentityFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "ak", id.AccessKey)
+}
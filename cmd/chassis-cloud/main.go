@@ -0,0 +1,179 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// chassis-cloud is a tool to troubleshoot huawei cloud integration of go chassis,
+// run it in the work dir of the service, so that it reads the same config as the service.
+//
+//	chassis-cloud auth explain [-v]
+//
+// explains how the credential is resolved, it exits with 1 if resolution fails
+//
+//	chassis-cloud keystore init [-dir dir]
+//
+// generates the root key components, salt and work key of keystore cipher, ${CIPHER_ROOT}/keystore by default
+//
+//	chassis-cloud encrypt [-dir dir] < plain
+//
+// encrypts the secret read from stdin with the keystore, the output is the sk to set in config with
+// akskCustomCipher "keystore"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/security/cipher/plugins/keystore"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/openlog"
+)
+
+const usage = `usage: chassis-cloud <command> [flags]
+
+commands:
+  auth explain    explain how the credential is resolved
+  keystore init   generate the keystore
+  encrypt         encrypt the secret read from stdin with the keystore
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	switch {
+	case len(args) >= 2 && args[0] == "auth" && args[1] == "explain":
+		return runExplain(args[2:], stdout, stderr)
+	case len(args) >= 2 && args[0] == "keystore" && args[1] == "init":
+		return runKeystoreInit(args[2:], stdout, stderr)
+	case len(args) >= 1 && args[0] == "encrypt":
+		return runEncrypt(args[1:], stdin, stdout, stderr)
+	}
+	fmt.Fprint(stderr, usage)
+	return 2
+}
+
+func runExplain(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("auth explain", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	verbose := fs.Bool("v", false, "print logs of go chassis")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !*verbose {
+		openlog.SetLogger(discard{})
+	}
+	if err := initConfig(stderr); err != nil {
+		fmt.Fprintf(stderr, "init config failed: %s\n", err)
+		return 1
+	}
+	return explain(stdout)
+}
+
+func runKeystoreInit(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("keystore init", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", keystore.Dir(), "keystore directory")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := keystore.Generate(*dir); err != nil {
+		fmt.Fprintf(stderr, "generate keystore failed: %s\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "keystore generated in %s\n", *dir)
+	return 0
+}
+
+// runEncrypt reads the secret from stdin rather than args, so that it is not left in shell history
+func runEncrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", keystore.Dir(), "keystore directory")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	b, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "read secret failed: %s\n", err)
+		return 1
+	}
+	plain := strings.TrimRight(string(b), "\r\n")
+	if plain == "" {
+		fmt.Fprintln(stderr, "no secret in stdin")
+		return 1
+	}
+	c, err := keystore.Load(*dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "load keystore failed: %s\n", err)
+		return 1
+	}
+	encrypted, err := c.Encrypt(plain)
+	if err != nil {
+		fmt.Fprintf(stderr, "encrypt failed: %s\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, encrypted)
+	return 0
+}
+
+// initConfig reads config files of CHASSIS_HOME, only env is used if there is no config file
+func initConfig(stderr io.Writer) error {
+	if err := config.InitArchaius(); err != nil {
+		fmt.Fprintf(stderr, "no config file (%s), read env only\n", err)
+		if err := archaius.Init(archaius.WithMemorySource(), archaius.WithENVSource()); err != nil {
+			return err
+		}
+	}
+	return config.ReadGlobalConfigFromArchaius()
+}
+
+func explain(w io.Writer) int {
+	e, err := auth.Explain()
+	for i, s := range e.Steps {
+		fmt.Fprintf(w, "%2d. %s\n", i+1, s)
+	}
+	fmt.Fprintln(w)
+	if err != nil {
+		fmt.Fprintf(w, "FAILED: %s\n", auth.Redact(err.Error()))
+		return 1
+	}
+	fmt.Fprintf(w, "type:     %s\n", e.Type)
+	if e.Provider != "" {
+		fmt.Fprintf(w, "source:   %s\n", e.Provider)
+		fmt.Fprintf(w, "ak:       %s\n", e.AccessKey)
+	}
+	if e.Project != "" {
+		fmt.Fprintf(w, "region:   %s\n", e.Region)
+		fmt.Fprintf(w, "project:  %s (from %s)\n", e.Project, e.ProjectSource)
+	}
+	fmt.Fprintf(w, "signer:   %s\n", e.Signer)
+	return 0
+}
+
+type discard struct{}
+
+func (discard) Debug(string, ...openlog.Option) {}
+func (discard) Info(string, ...openlog.Option)  {}
+func (discard) Warn(string, ...openlog.Option)  {}
+func (discard) Error(string, ...openlog.Option) {}
+func (discard) Fatal(string, ...openlog.Option) {}
@@ -0,0 +1,89 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/security/cipher/plugins/keystore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	home, err := ioutil.TempDir("", "chassis-cloud")
+	assert.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv("CHASSIS_HOME", home)
+	defer os.Unsetenv("CHASSIS_HOME")
+
+	for name, args := range map[string][]string{
+		"no command":      nil,
+		"unknown command": {"auth", "login"},
+		"unknown flag":    {"auth", "explain", "-x"},
+		"no sub command":  {"keystore"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			assert.Equal(t, 2, run(args, nil, stdout, stderr))
+			assert.NotEmpty(t, stderr.String())
+		})
+	}
+	t.Run("no credential", func(t *testing.T) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		assert.Equal(t, 1, run([]string{"auth", "explain"}, nil, stdout, stderr))
+		assert.Contains(t, stdout.String(), "FAILED")
+	})
+	t.Run("explain credential of env", func(t *testing.T) {
+		os.Setenv(auth.EnvAccessKey, "CLIACCESSKEY0001")
+		os.Setenv(auth.EnvSecretKey, "cli-secret")
+		defer os.Unsetenv(auth.EnvAccessKey)
+		defer os.Unsetenv(auth.EnvSecretKey)
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		assert.Equal(t, 0, run([]string{"auth", "explain"}, nil, stdout, stderr))
+		assert.Contains(t, stdout.String(), "source:   "+auth.ProviderEnv)
+		assert.Contains(t, stdout.String(), "CLIA****0001")
+		assert.NotContains(t, stdout.String(), "cli-secret")
+	})
+	t.Run("encrypt with keystore", func(t *testing.T) {
+		dir := filepath.Join(home, "keystore")
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		assert.Equal(t, 1, run([]string{"encrypt", "-dir", dir}, strings.NewReader("cli-secret\n"), stdout, stderr),
+			"no keystore")
+		assert.Equal(t, 0, run([]string{"keystore", "init", "-dir", dir}, nil, stdout, stderr))
+		assert.Equal(t, 1, run([]string{"keystore", "init", "-dir", dir}, nil, stdout, stderr),
+			"never overwrite keystore")
+
+		stdout.Reset()
+		assert.Equal(t, 0, run([]string{"encrypt", "-dir", dir}, strings.NewReader("cli-secret\n"), stdout, stderr))
+		encrypted := strings.TrimSpace(stdout.String())
+		assert.NotContains(t, encrypted, "cli-secret")
+		c, err := keystore.Load(dir)
+		assert.NoError(t, err)
+		plain, err := c.Decrypt(encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "cli-secret", plain)
+
+		assert.Equal(t, 1, run([]string{"encrypt", "-dir", dir}, strings.NewReader(""), stdout, stderr))
+	})
+}
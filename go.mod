@@ -3,11 +3,16 @@ module github.com/go-chassis/go-chassis-cloud
 go 1.13
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/go-chassis/cari v0.0.0-20201210041921-7b6fbef2df11
 	github.com/go-chassis/foundation v0.2.2
 	github.com/go-chassis/go-archaius v1.3.6-0.20201210061741-7450779aaeb8
 	github.com/go-chassis/go-chassis/v2 v2.1.1
 	github.com/go-chassis/openlog v1.1.2
 	github.com/huaweicse/auth v1.1.2
+	github.com/prometheus/client_golang v0.9.1
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/crypto v0.0.0-20190820162420-60c769a6c586
+	google.golang.org/grpc v1.19.0
 	gopkg.in/yaml.v2 v2.3.0
 )
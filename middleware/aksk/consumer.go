@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aksk
+
+import (
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/status"
+	"github.com/go-chassis/openlog"
+)
+
+// ConsumerHandlerName is the name used in consumer handler chain
+const ConsumerHandlerName = "aksk-consumer"
+
+// ConsumerHandler sets ShaAKSK headers of current credential to invocation headers,
+// so that highway and grpc requests carry the same identity as http requests.
+// project and profile set by auth.WithProject and auth.WithProfile to inv.Ctx are used
+type ConsumerHandler struct{}
+
+// Handle sets identity headers, the invocation goes on without them if no credential is loaded
+func (h *ConsumerHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	headers, err := auth.IdentityHeadersContext(inv.Ctx)
+	if err != nil && err != auth.ErrAuthNotLoaded {
+		err = auth.RedactError(err)
+		openlog.Error("can not sign invocation: " + err.Error())
+		handler.WriteBackErr(err, status.Status(inv.Protocol, status.InternalServerError), cb)
+		return
+	}
+	for k, v := range headers {
+		inv.SetHeader(k, v)
+	}
+	chain.Next(inv, cb)
+}
+
+// Name returns handler name
+func (h *ConsumerHandler) Name() string {
+	return ConsumerHandlerName
+}
+
+func newConsumerHandler() handler.Handler {
+	return &ConsumerHandler{}
+}
@@ -0,0 +1,74 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package aksk provides go chassis handlers to carry and verify the ShaAKSK identity of callers in any protocol,
+// add "aksk-consumer" to consumer handler chain and "aksk-provider" to provider handler chain to enable them
+package aksk
+
+import (
+	"fmt"
+
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/status"
+	"github.com/go-chassis/openlog"
+)
+
+// ProviderHandlerName is the name used in provider handler chain
+const ProviderHandlerName = "aksk-provider"
+
+// ProviderHandler rejects requests whose ShaAKSK headers are not signed by a trusted ak sk,
+// the authenticated caller can be got by auth.IdentityFromContext(inv.Ctx)
+type ProviderHandler struct{}
+
+// Handle verifies invocation headers
+func (h *ProviderHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	v, err := auth.GetVerifier()
+	if err != nil {
+		err = auth.RedactError(err)
+		openlog.Error("can not load trusted ak sk: " + err.Error())
+		handler.WriteBackErr(err, status.Status(inv.Protocol, status.InternalServerError), cb)
+		return
+	}
+	id, err := v.VerifyHeaders(inv.Headers())
+	if err != nil {
+		openlog.Warn(fmt.Sprintf("reject request from [%s]: %s", inv.SourceMicroService, err))
+		handler.WriteBackErr(err, status.Status(inv.Protocol, status.Unauthorized), cb)
+		return
+	}
+	inv.Ctx = auth.WithIdentity(inv.Ctx, id)
+	chain.Next(inv, cb)
+}
+
+// Name returns handler name
+func (h *ProviderHandler) Name() string {
+	return ProviderHandlerName
+}
+
+func newProviderHandler() handler.Handler {
+	return &ProviderHandler{}
+}
+
+func init() {
+	if err := handler.RegisterHandler(ProviderHandlerName, newProviderHandler); err != nil {
+		openlog.Error(err.Error())
+	}
+	if err := handler.RegisterHandler(ConsumerHandlerName, newConsumerHandler); err != nil {
+		openlog.Error(err.Error())
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aksk_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/middleware/aksk"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+type identityHandler struct{}
+
+func (h *identityHandler) Name() string {
+	return "identity"
+}
+
+func (h *identityHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	id, ok := auth.IdentityFromContext(inv.Ctx)
+	if !ok {
+		handler.WriteBackErr(nil, http.StatusInternalServerError, cb)
+		return
+	}
+	cb(&invocation.Response{Status: http.StatusOK, Result: id})
+}
+
+func newIdentityHandler() handler.Handler {
+	return &identityHandler{}
+}
+
+func TestProviderHandler_Handle(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "archaius"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.trusted", []interface{}{
+		map[interface{}]interface{}{"accessKey": "ak", "secretKey": "sk", "project": "p"},
+	}))
+	handler.RegisterHandler("identity", newIdentityHandler)
+	c, err := handler.CreateChain(common.Provider, "aksk", aksk.ProviderHandlerName, "identity")
+	assert.NoError(t, err)
+
+	sign, err := auth.GetShaAKSKSignFunc("ak", "sk", "p")
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("GET", "http://127.0.0.1", nil)
+	assert.NoError(t, sign(r))
+	headers := make(map[string]string)
+	for k := range r.Header {
+		headers[k] = r.Header.Get(k)
+	}
+
+	t.Run("trusted", func(t *testing.T) {
+		inv := invocation.New(common.NewContext(headers))
+		inv.Protocol = common.ProtocolRest
+		called := false
+		c.Next(inv, func(resp *invocation.Response) {
+			called = true
+			assert.NoError(t, resp.Err)
+			assert.Equal(t, &auth.Identity{AccessKey: "ak", Project: "p"}, resp.Result)
+		})
+		assert.True(t, called)
+	})
+	t.Run("no header", func(t *testing.T) {
+		inv := invocation.New(context.Background())
+		inv.Protocol = common.ProtocolRest
+		called := false
+		c.Next(inv, func(resp *invocation.Response) {
+			called = true
+			assert.Equal(t, auth.ErrNoAKSKHeader, resp.Err)
+			assert.Equal(t, http.StatusUnauthorized, resp.Status)
+		})
+		assert.True(t, called)
+	})
+}
+
+func TestConsumerHandler_Handle(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "archaius"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.accessKey", "ak"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.secretKey", "sk"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.project", "p"))
+	assert.NoError(t, auth.LoadAkskAuth())
+	handler.RegisterHandler("identity", newIdentityHandler)
+	c, err := handler.CreateChain(common.Consumer, "aksk-highway",
+		aksk.ConsumerHandlerName, aksk.ProviderHandlerName, "identity")
+	assert.NoError(t, err)
+
+	inv := invocation.New(context.Background())
+	inv.Protocol = "highway"
+	called := false
+	c.Next(inv, func(resp *invocation.Response) {
+		called = true
+		assert.NoError(t, resp.Err)
+		assert.Equal(t, &auth.Identity{AccessKey: "ak", Project: "p"}, resp.Result)
+	})
+	assert.True(t, called)
+	assert.Equal(t, "ak", inv.Header(auth.HeaderServiceAk))
+}
@@ -0,0 +1,133 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package akskgrpc provides grpc interceptors to carry and verify the ShaAKSK identity in grpc metadata,
+// it is for grpc clients and servers not managed by go chassis,
+// go chassis grpc invocations can use the handlers in package aksk
+package akskgrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/openlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor sets ShaAKSK headers of current credential to outgoing metadata
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := withIdentity(ctx)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor sets ShaAKSK headers of current credential to outgoing metadata
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := withIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// withIdentity leaves ctx as it is if no credential is loaded
+func withIdentity(ctx context.Context) (context.Context, error) {
+	headers, err := auth.IdentityHeadersContext(ctx)
+	if err == auth.ErrAuthNotLoaded {
+		return ctx, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	kv := make([]string, 0, 2*len(headers))
+	for k, v := range headers {
+		// grpc metadata keys are lower case
+		kv = append(kv, strings.ToLower(k), v)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...), nil
+}
+
+// UnaryServerInterceptor rejects requests whose ShaAKSK metadata is not signed by a trusted ak sk,
+// the authenticated caller can be got by auth.IdentityFromContext
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := verify(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is same as UnaryServerInterceptor for streams
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		ctx, err := verify(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func verify(ctx context.Context, method string) (context.Context, error) {
+	v, err := auth.GetVerifier()
+	if err != nil {
+		err = auth.RedactError(err)
+		openlog.Error("can not load trusted ak sk: " + err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	headers := make(map[string]string, len(md))
+	for k, values := range md {
+		if len(values) > 0 {
+			headers[k] = values[0]
+		}
+	}
+	id, err := v.VerifyHeaders(headers)
+	if err != nil {
+		openlog.Warn(fmt.Sprintf("reject grpc call of [%s]: %s", method, err))
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return auth.WithIdentity(ctx, id), nil
+}
+
+// serverStream carries the context with identity
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with identity
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,71 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package akskgrpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/middleware/akskgrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestInterceptors(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.providers", "archaius"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.accessKey", "ak"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.secretKey", "sk"))
+	assert.NoError(t, archaius.Set("servicecomb.credentials.project", "p"))
+	assert.NoError(t, auth.LoadAkskAuth())
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	assert.NoError(t, akskgrpc.UnaryClientInterceptor()(context.Background(), "/svc/Method", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"ak"}, outgoing.Get(auth.HeaderServiceAk))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, ok := auth.IdentityFromContext(ctx)
+		assert.True(t, ok)
+		return id, nil
+	}
+	t.Run("trusted", func(t *testing.T) {
+		resp, err := akskgrpc.UnaryServerInterceptor()(metadata.NewIncomingContext(context.Background(), outgoing), nil, info, handler)
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Identity{AccessKey: "ak", Project: "p"}, resp)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		md := outgoing.Copy()
+		md.Set(auth.HeaderServiceShaAKSK, "forged")
+		_, err := akskgrpc.UnaryServerInterceptor()(metadata.NewIncomingContext(context.Background(), md), nil, info, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("no metadata", func(t *testing.T) {
+		_, err := akskgrpc.UnaryServerInterceptor()(context.Background(), nil, info, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -22,11 +22,18 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
 	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"net/http"
 )
 
+// ErrUnauthorized means engine manager rejects the credential
+var ErrUnauthorized = errors.New("cse: unauthorized")
+
 type Client struct {
 	c      *httpclient.Requests
 	opts   Options
@@ -35,12 +42,15 @@ type Client struct {
 
 func New(opts Options) (*Client, error) {
 	ho := &httpclient.Options{
-		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		TLSConfig: opts.TLSConfig,
+	}
+	if ho.TLSConfig == nil {
+		ho.TLSConfig = &tls.Config{InsecureSkipVerify: true}
 	}
 	if opts.Signer != nil {
 		ho.SignRequest = opts.Signer
 	}
-	c, err := httpclient.New(ho)
+	c, err := resign.New(ho)
 
 	return &Client{
 		c:    c,
@@ -48,16 +58,31 @@ func New(opts Options) (*Client, error) {
 	}, err
 }
 
+//Project returns name and id of the project this client works in
+func (c *Client) Project() (name, id string, err error) {
+	if c.opts.Project != "" && c.opts.ProjectID != "" {
+		return c.opts.Project, c.opts.ProjectID, nil
+	}
+	if c.opts.Project != "" {
+		id, err = auth.ProjectID(c.opts.Project)
+		return c.opts.Project, id, err
+	}
+	return auth.CurrentProject()
+}
+
 //GetEngineMD return engine information
 func (c *Client) GetEngineMD(engineName string) (*EngineMD, error) {
 	resp, err := c.c.Get(context.Background(), c.opts.Endpoint+"/cseengine/v1/engine-metadata?name="+engineName, nil)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("status: %s, resp: %s"+resp.Status, httputil.ReadBody(resp))
-	}
 	b := httputil.ReadBody(resp)
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w, resp: %s", ErrUnauthorized, b)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s, resp: %s", resp.Status, b)
+	}
 	engine := &EngineMD{}
 	err = json.Unmarshal(b, engine)
 	if err != nil {
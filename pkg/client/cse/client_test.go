@@ -18,10 +18,16 @@
 package cse_test
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis-cloud/auth"
 	"github.com/go-chassis/go-chassis-cloud/pkg/client/cse"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -37,3 +43,36 @@ func TestNew(t *testing.T) {
 	t.Log(engine.CSE.PrivateEndpoint)
 	t.Log(engine.CSE.PublicEndpoint)
 }
+
+func TestClient_GetEngineMD_Unauthorized(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sak", r.Header.Get(auth.HeaderServiceAk))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer s.Close()
+	sign, err := auth.GetSignFunc("sak", "ssk", "cn-north-1")
+	assert.NoError(t, err)
+	c, err := cse.New(cse.Options{Endpoint: s.URL, Signer: sign})
+	assert.NoError(t, err)
+	_, err = c.GetEngineMD("default")
+	assert.True(t, errors.Is(err, cse.ErrUnauthorized))
+}
+
+func TestClient_TLSConfig(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+	c, err := cse.New(cse.Options{Endpoint: s.URL, TLSConfig: &tls.Config{}})
+	assert.NoError(t, err)
+	_, err = c.GetEngineMD("default")
+	assert.Error(t, err, "server is not trusted")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	c, err = cse.New(cse.Options{Endpoint: s.URL, TLSConfig: &tls.Config{RootCAs: pool}})
+	assert.NoError(t, err)
+	_, err = c.GetEngineMD("default")
+	assert.NoError(t, err)
+}
@@ -18,10 +18,18 @@
 package cse
 
 import (
+	"crypto/tls"
+
 	"github.com/go-chassis/go-chassis-cloud/auth"
 )
 
 type Options struct {
 	Endpoint string
 	Signer   auth.SignRequest
+	// Project and ProjectID are the ones of current credential if they are empty
+	Project   string
+	ProjectID string
+	// TLSConfig verifies engine manager, verification is skipped if it is nil.
+	// requests signed with a credential to validate must verify the server
+	TLSConfig *tls.Config
 }
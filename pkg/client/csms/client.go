@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package csms implement client APIs of huawei cloud secret management service
+package csms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+)
+
+// VersionLatest is the version id of the latest version
+const VersionLatest = "latest"
+
+// errors of CSMS
+var (
+	ErrUnauthorized   = errors.New("csms: unauthorized")
+	ErrSecretNotFound = errors.New("csms: secret not found")
+)
+
+type Client struct {
+	c    *httpclient.Requests
+	opts Options
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Endpoint == "" || opts.ProjectID == "" {
+		return nil, errors.New("csms endpoint or project id is empty")
+	}
+	signer := opts.Signer
+	if signer == nil {
+		// never fall back to the global sign func, it may be the one waiting for this client
+		signer = func(*http.Request) error { return nil }
+	}
+	c, err := resign.New(&httpclient.Options{
+		TLSConfig:   opts.TLSConfig,
+		SignRequest: signer,
+	})
+	return &Client{
+		c:    c,
+		opts: opts,
+	}, err
+}
+
+// GetSecretVersion returns a version of secret, version can be VersionLatest
+func (c *Client) GetSecretVersion(name, version string) (*SecretVersion, error) {
+	if version == "" {
+		version = VersionLatest
+	}
+	u := fmt.Sprintf("%s/v1/%s/secrets/%s/versions/%s", strings.TrimSuffix(c.opts.Endpoint, "/"),
+		c.opts.ProjectID, url.PathEscape(name), url.PathEscape(version))
+	resp, err := c.c.Get(context.Background(), u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b := httputil.ReadBody(resp)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("%w, resp: %s", ErrUnauthorized, b)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s/%s", ErrSecretNotFound, name, version)
+	default:
+		return nil, fmt.Errorf("status: %s, resp: %s", resp.Status, b)
+	}
+	vr := &versionResponse{}
+	if err := json.Unmarshal(b, vr); err != nil {
+		return nil, err
+	}
+	return &SecretVersion{
+		ID:           vr.Version.Metadata.ID,
+		SecretName:   vr.Version.Metadata.SecretName,
+		SecretString: vr.Version.SecretString,
+	}, nil
+}
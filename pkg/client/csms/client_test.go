@@ -0,0 +1,52 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package csms_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/csms"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetSecretVersion(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/pid/secrets/cse-credential/versions/latest", "/v1/pid/secrets/cse-credential/versions/v2":
+			w.Write([]byte(`{"version":{"version_metadata":{"id":"v2","secret_name":"cse-credential"},"secret_string":"{}"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+	c, err := csms.New(csms.Options{Endpoint: s.URL, ProjectID: "pid"})
+	assert.NoError(t, err)
+
+	v, err := c.GetSecretVersion("cse-credential", "")
+	assert.NoError(t, err)
+	assert.Equal(t, &csms.SecretVersion{ID: "v2", SecretName: "cse-credential", SecretString: "{}"}, v)
+	v, err = c.GetSecretVersion("cse-credential", "v2")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", v.ID)
+
+	_, err = c.GetSecretVersion("cse-credential", "v1")
+	assert.True(t, errors.Is(err, csms.ErrSecretNotFound))
+}
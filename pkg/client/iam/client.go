@@ -0,0 +1,165 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam implement client APIs for huawei cloud IAM service
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+)
+
+// HeaderSubjectToken is the response header carrying the token
+const HeaderSubjectToken = "X-Subject-Token"
+
+// errors of IAM
+var (
+	// ErrUnauthorized means IAM rejects the credential
+	ErrUnauthorized = errors.New("iam: unauthorized")
+	// ErrProjectNotFound means no project of the name is visible to the credential
+	ErrProjectNotFound = errors.New("iam: project not found")
+)
+
+type Client struct {
+	c    *httpclient.Requests
+	opts Options
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("iam endpoint is empty")
+	}
+	signer := opts.Signer
+	if signer == nil {
+		// never fall back to the global sign func, it may be the one waiting for this client
+		signer = func(*http.Request) error { return nil }
+	}
+	c, err := resign.New(&httpclient.Options{
+		TLSConfig:   opts.TLSConfig,
+		SignRequest: signer,
+	})
+	return &Client{
+		c:    c,
+		opts: opts,
+	}, err
+}
+
+// GetPasswordToken returns a token of IAM user scoped to project
+func (c *Client) GetPasswordToken(domainName, userName, pwd, project string) (*Token, error) {
+	return c.getToken(&tokenRequest{Auth: tokenAuth{
+		Identity: identity{
+			Methods: []string{"password"},
+			Password: &password{User: user{
+				Name:     userName,
+				Password: pwd,
+				Domain:   domain{Name: domainName},
+			}},
+		},
+		Scope: projectScope(project),
+	}})
+}
+
+// GetAKSKToken returns a token scoped to project, the request must be signed with SDK-HMAC-SHA256
+func (c *Client) GetAKSKToken(ak, project string) (*Token, error) {
+	return c.getToken(&tokenRequest{Auth: tokenAuth{
+		Identity: identity{
+			Methods: []string{"hw_ak_sk"},
+			AKSK:    &akSK{Access: key{Key: ak}},
+		},
+		Scope: projectScope(project),
+	}})
+}
+
+// GetProjectID returns the id of a project by its name, like cn-north-4 or cn-north-4_sub,
+// the request must be signed with SDK-HMAC-SHA256
+func (c *Client) GetProjectID(name string) (string, error) {
+	resp, err := c.c.Get(context.Background(), c.opts.Endpoint+"/v3/projects?name="+url.QueryEscape(name), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b := httputil.ReadBody(resp)
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w, resp: %s", ErrUnauthorized, b)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status: %s, resp: %s", resp.Status, b)
+	}
+	pr := &projectsResponse{}
+	if err := json.Unmarshal(b, pr); err != nil {
+		return "", err
+	}
+	for _, p := range pr.Projects {
+		if p.Name == name && p.ID != "" {
+			return p.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+}
+
+func projectScope(project string) *scope {
+	if project == "" {
+		return nil
+	}
+	return &scope{Project: &Project{Name: project}}
+}
+
+func (c *Client) getToken(req *tokenRequest) (*Token, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	h := http.Header{}
+	h.Set("Content-Type", "application/json;charset=utf8")
+	resp, err := c.c.Post(context.Background(), c.opts.Endpoint+"/v3/auth/tokens", h, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b := httputil.ReadBody(resp)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w, resp: %s", ErrUnauthorized, b)
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s, resp: %s", resp.Status, b)
+	}
+	tr := &tokenResponse{}
+	if err := json.Unmarshal(b, tr); err != nil {
+		return nil, err
+	}
+	t := &Token{
+		Value:   resp.Header.Get(HeaderSubjectToken),
+		Project: tr.Token.Project,
+	}
+	if t.Value == "" {
+		return nil, errors.New("no token in response")
+	}
+	t.ExpiresAt, err = time.Parse(time.RFC3339, tr.Token.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expires_at [%s]: %v", tr.Token.ExpiresAt, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetToken(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/auth/tokens", r.URL.Path)
+		b, _ := ioutil.ReadAll(r.Body)
+		req := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(b, &req))
+		auth := req["auth"].(map[string]interface{})
+		methods := auth["identity"].(map[string]interface{})["methods"].([]interface{})
+		switch methods[0] {
+		case "password":
+			user := auth["identity"].(map[string]interface{})["password"].(map[string]interface{})["user"].(map[string]interface{})
+			if user["password"] != "pwd" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "hw_ak_sk":
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		w.Header().Set(iam.HeaderSubjectToken, "token-"+methods[0].(string))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":{"expires_at":"2030-01-02T03:04:05.000000Z","project":{"id":"pid","name":"cn-north-1"}}}`))
+	}))
+	defer s.Close()
+
+	t.Run("password", func(t *testing.T) {
+		c, err := iam.New(iam.Options{Endpoint: s.URL})
+		assert.NoError(t, err)
+		tk, err := c.GetPasswordToken("domain", "user", "pwd", "cn-north-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "token-password", tk.Value)
+		assert.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), tk.ExpiresAt)
+		assert.Equal(t, "pid", tk.Project.ID)
+
+		_, err = c.GetPasswordToken("domain", "user", "wrong", "cn-north-1")
+		assert.True(t, errors.Is(err, iam.ErrUnauthorized))
+	})
+	t.Run("aksk", func(t *testing.T) {
+		c, err := iam.New(iam.Options{Endpoint: s.URL, Signer: func(r *http.Request) error {
+			r.Header.Set("Authorization", "signed")
+			return nil
+		}})
+		assert.NoError(t, err)
+		tk, err := c.GetAKSKToken("ak", "cn-north-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "token-hw_ak_sk", tk.Value)
+	})
+}
+
+func TestClient_GetProjectID(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/projects", r.URL.Path)
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("name") != "cn-north-4_sub" {
+			w.Write([]byte(`{"projects":[]}`))
+			return
+		}
+		w.Write([]byte(`{"projects":[{"id":"pid","name":"cn-north-4_sub","domain_id":"did"}]}`))
+	}))
+	defer s.Close()
+
+	c, err := iam.New(iam.Options{Endpoint: s.URL, Signer: func(r *http.Request) error {
+		r.Header.Set("Authorization", "signed")
+		return nil
+	}})
+	assert.NoError(t, err)
+	id, err := c.GetProjectID("cn-north-4_sub")
+	assert.NoError(t, err)
+	assert.Equal(t, "pid", id)
+	_, err = c.GetProjectID("cn-north-1")
+	assert.True(t, errors.Is(err, iam.ErrProjectNotFound))
+
+	c, err = iam.New(iam.Options{Endpoint: s.URL})
+	assert.NoError(t, err)
+	_, err = c.GetProjectID("cn-north-4_sub")
+	assert.True(t, errors.Is(err, iam.ErrUnauthorized))
+}
@@ -0,0 +1,84 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import "time"
+
+// Token is an IAM token, Value is returned in X-Subject-Token header
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+	Project   *Project
+}
+
+type Project struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type tokenRequest struct {
+	Auth tokenAuth `json:"auth"`
+}
+
+type tokenAuth struct {
+	Identity identity `json:"identity"`
+	Scope    *scope   `json:"scope,omitempty"`
+}
+
+type identity struct {
+	Methods  []string  `json:"methods"`
+	Password *password `json:"password,omitempty"`
+	AKSK     *akSK     `json:"hw_ak_sk,omitempty"`
+}
+
+type password struct {
+	User user `json:"user"`
+}
+
+type user struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Domain   domain `json:"domain"`
+}
+
+type domain struct {
+	Name string `json:"name"`
+}
+
+type akSK struct {
+	Access key `json:"access"`
+}
+
+type key struct {
+	Key string `json:"key"`
+}
+
+type scope struct {
+	Project *Project `json:"project,omitempty"`
+}
+
+type tokenResponse struct {
+	Token struct {
+		ExpiresAt string   `json:"expires_at"`
+		Project   *Project `json:"project"`
+	} `json:"token"`
+}
+
+type projectsResponse struct {
+	Projects []*Project `json:"projects"`
+}
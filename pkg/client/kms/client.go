@@ -0,0 +1,133 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kms implement client APIs of huawei cloud key management service for envelope encryption
+package kms
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+)
+
+// DataKeyBytes is the length of data keys, they are AES-256 keys
+const DataKeyBytes = 32
+
+// ErrUnauthorized means KMS rejects the credential
+var ErrUnauthorized = errors.New("kms: unauthorized")
+
+type Client struct {
+	c    *httpclient.Requests
+	opts Options
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Endpoint == "" || opts.ProjectID == "" {
+		return nil, errors.New("kms endpoint or project id is empty")
+	}
+	signer := opts.Signer
+	if signer == nil {
+		// never fall back to the global sign func, it may need a secret decrypted by this client
+		signer = func(*http.Request) error { return nil }
+	}
+	c, err := resign.New(&httpclient.Options{
+		TLSConfig:   opts.TLSConfig,
+		SignRequest: signer,
+	})
+	return &Client{
+		c:    c,
+		opts: opts,
+	}, err
+}
+
+// CreateDataKey generates a data key encrypted by master key keyID
+func (c *Client) CreateDataKey(keyID string) (*DataKey, error) {
+	resp := &createDataKeyResponse{}
+	err := c.post("create-datakey", &createDataKeyRequest{
+		KeyID:         keyID,
+		DataKeyLength: strconv.Itoa(DataKeyBytes * 8),
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := hex.DecodeString(resp.PlainText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plain_text: %v", err)
+	}
+	return &DataKey{KeyID: keyID, Plain: plain, CipherText: resp.CipherText}, nil
+}
+
+// DecryptDataKey decrypts a data key encrypted by master key keyID
+func (c *Client) DecryptDataKey(keyID, cipherText string) ([]byte, error) {
+	resp := &decryptDataKeyResponse{}
+	err := c.post("decrypt-datakey", &decryptDataKeyRequest{
+		KeyID:               keyID,
+		CipherText:          cipherText,
+		DataKeyCipherLength: strconv.Itoa(DataKeyBytes),
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := hex.DecodeString(resp.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data_key: %v", err)
+	}
+	if resp.DataKeyDigest != "" {
+		sum := sha256.Sum256(plain)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), resp.DataKeyDigest) {
+			return nil, errors.New("data key digest mismatch")
+		}
+	}
+	return plain, nil
+}
+
+func (c *Client) post(action string, req, result interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	h := http.Header{}
+	h.Set("Content-Type", "application/json;charset=utf8")
+	u := fmt.Sprintf("%s/v1.0/%s/kms/%s", strings.TrimSuffix(c.opts.Endpoint, "/"), c.opts.ProjectID, action)
+	resp, err := c.c.Post(context.Background(), u, h, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b := httputil.ReadBody(resp)
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w, resp: %s", ErrUnauthorized, b)
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := &errorResponse{}
+		if json.Unmarshal(b, e) == nil && e.Error.Code != "" {
+			return fmt.Errorf("kms %s failed: %s %s", action, e.Error.Code, e.Error.Message)
+		}
+		return fmt.Errorf("status: %s, resp: %s", resp.Status, b)
+	}
+	return json.Unmarshal(b, result)
+}
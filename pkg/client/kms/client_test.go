@@ -0,0 +1,56 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kms_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/kms"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/kms/kmstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_DataKey(t *testing.T) {
+	s := kmstest.NewServer("key1")
+	defer s.Close()
+	s.Authorize = func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "signed"
+	}
+	c, err := kms.New(kms.Options{Endpoint: s.URL, ProjectID: "pid", Signer: func(r *http.Request) error {
+		r.Header.Set("Authorization", "signed")
+		return nil
+	}})
+	assert.NoError(t, err)
+
+	dk, err := c.CreateDataKey("key1")
+	assert.NoError(t, err)
+	assert.Len(t, dk.Plain, kms.DataKeyBytes)
+	plain, err := c.DecryptDataKey("key1", dk.CipherText)
+	assert.NoError(t, err)
+	assert.Equal(t, dk.Plain, plain)
+
+	_, err = c.DecryptDataKey("key2", dk.CipherText)
+	assert.Error(t, err)
+
+	c, err = kms.New(kms.Options{Endpoint: s.URL, ProjectID: "pid"})
+	assert.NoError(t, err)
+	_, err = c.DecryptDataKey("key1", dk.CipherText)
+	assert.True(t, errors.Is(err, kms.ErrUnauthorized))
+}
@@ -0,0 +1,127 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kmstest provides a local KMS stand-in for tests,
+// it implements create-datakey and decrypt-datakey with in memory master keys
+package kmstest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Server is a local KMS stand-in
+type Server struct {
+	*httptest.Server
+	// Authorize checks requests, all requests are allowed if it is nil
+	Authorize func(r *http.Request) bool
+
+	mu         sync.Mutex
+	masterKeys map[string]cipher.AEAD
+	decrypts   int64
+}
+
+// NewServer starts a KMS stand-in with a master key of each key id
+func NewServer(keyIDs ...string) *Server {
+	s := &Server{masterKeys: make(map[string]cipher.AEAD)}
+	for _, id := range keyIDs {
+		k := make([]byte, 32)
+		rand.Read(k)
+		block, _ := aes.NewCipher(k)
+		s.masterKeys[id], _ = cipher.NewGCM(block)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Decrypts returns how many times decrypt-datakey is called
+func (s *Server) Decrypts() int {
+	return int(atomic.LoadInt64(&s.decrypts))
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if s.Authorize != nil && !s.Authorize(r) {
+		writeError(w, http.StatusUnauthorized, "APIGW.0301", "incorrect IAM authentication information")
+		return
+	}
+	req := make(map[string]string)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "KMS.0201", err.Error())
+		return
+	}
+	s.mu.Lock()
+	master, ok := s.masterKeys[req["key_id"]]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "KMS.0207", "key not found")
+		return
+	}
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/kms/create-datakey"):
+		plain := make([]byte, 32)
+		rand.Read(plain)
+		nonce := make([]byte, master.NonceSize())
+		rand.Read(nonce)
+		writeJSON(w, map[string]string{
+			"key_id":      req["key_id"],
+			"plain_text":  hex.EncodeToString(plain),
+			"cipher_text": hex.EncodeToString(master.Seal(nonce, nonce, plain, nil)),
+		})
+	case strings.HasSuffix(r.URL.Path, "/kms/decrypt-datakey"):
+		atomic.AddInt64(&s.decrypts, 1)
+		b, err := hex.DecodeString(req["cipher_text"])
+		if err != nil || len(b) < master.NonceSize() {
+			writeError(w, http.StatusBadRequest, "KMS.0205", "invalid cipher_text")
+			return
+		}
+		plain, err := master.Open(nil, b[:master.NonceSize()], b[master.NonceSize():], nil)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "KMS.0205", "invalid cipher_text")
+			return
+		}
+		sum := sha256.Sum256(plain)
+		writeJSON(w, map[string]string{
+			"data_key":       hex.EncodeToString(plain),
+			"datakey_length": "32",
+			"datakey_dgst":   hex.EncodeToString(sum[:]),
+		})
+	default:
+		writeError(w, http.StatusNotFound, "KMS.0404", "unknown action")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"error_code": code, "error_msg": msg},
+	})
+}
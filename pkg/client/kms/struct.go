@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kms
+
+// DataKey is a data encryption key generated by KMS
+type DataKey struct {
+	KeyID string
+	// Plain is the plain data key, never persist it
+	Plain []byte
+	// CipherText is the data key encrypted by the master key, in hex
+	CipherText string
+}
+
+type createDataKeyRequest struct {
+	KeyID         string `json:"key_id"`
+	DataKeyLength string `json:"datakey_length"`
+}
+
+type createDataKeyResponse struct {
+	KeyID      string `json:"key_id"`
+	PlainText  string `json:"plain_text"`
+	CipherText string `json:"cipher_text"`
+}
+
+type decryptDataKeyRequest struct {
+	KeyID               string `json:"key_id"`
+	CipherText          string `json:"cipher_text"`
+	DataKeyCipherLength string `json:"datakey_cipher_length"`
+}
+
+type decryptDataKeyResponse struct {
+	DataKey       string `json:"data_key"`
+	DataKeyLength string `json:"datakey_length"`
+	DataKeyDigest string `json:"datakey_dgst"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    string `json:"error_code"`
+		Message string `json:"error_msg"`
+	} `json:"error"`
+}
@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metadata implement client APIs for huawei cloud ECS instance metadata service
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+)
+
+// errors of metadata service
+var (
+	// ErrNoSecurityKey means the instance is not bound to an agency
+	ErrNoSecurityKey = errors.New("instance has no security key")
+	// ErrUnexpectedStatus means the response is neither a security key nor not found,
+	// like the one of a metadata service which is not the one of huawei cloud
+	ErrUnexpectedStatus = errors.New("unexpected status of metadata service")
+)
+
+type Client struct {
+	c    *httpclient.Requests
+	opts Options
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	c, err := httpclient.New(&httpclient.Options{
+		RequestTimeout: opts.Timeout,
+		// metadata service needs no auth, and the global sign func
+		// may be the one waiting for this client
+		SignRequest: func(*http.Request) error { return nil },
+	})
+	return &Client{
+		c:    c,
+		opts: opts,
+	}, err
+}
+
+// GetSecurityKey return temporary credential of the agency bound to this instance
+func (c *Client) GetSecurityKey() (*SecurityKey, error) {
+	resp, err := c.c.Get(context.Background(), c.opts.Endpoint+"/openstack/latest/securitykey", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b := httputil.ReadBody(resp)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoSecurityKey
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s, resp: %s", ErrUnexpectedStatus, resp.Status, b)
+	}
+	sk := &SecurityKey{}
+	err = json.Unmarshal(b, sk)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Credential == nil || sk.Credential.Access == "" || sk.Credential.Secret == "" {
+		return nil, ErrNoSecurityKey
+	}
+	return sk, nil
+}
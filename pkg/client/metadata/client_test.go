@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetSecurityKey(t *testing.T) {
+	bound := true
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openstack/latest/securitykey", r.URL.Path)
+		if !bound {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"credential":{"access":"ak","secret":"sk","securitytoken":"token",` +
+			`"expires_at":"2020-05-26T07:51:15.000000Z"}}`))
+	}))
+	defer s.Close()
+	c, err := metadata.New(metadata.Options{Endpoint: s.URL})
+	assert.NoError(t, err)
+
+	sk, err := c.GetSecurityKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "ak", sk.Credential.Access)
+	assert.Equal(t, "sk", sk.Credential.Secret)
+	assert.Equal(t, "token", sk.Credential.SecurityToken)
+	assert.Equal(t, "2020-05-26T07:51:15.000000Z", sk.Credential.ExpiresAt)
+
+	bound = false
+	_, err = c.GetSecurityKey()
+	assert.Equal(t, metadata.ErrNoSecurityKey, err)
+}
@@ -0,0 +1,164 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resign sends requests of huawei cloud clients, a request rejected for a cause which is fixed,
+// like a skewed clock or a rotated ak, is signed again and retried once.
+// registry and config center clients of go-chassis keep their own transport, they are not retried,
+// but their later requests are signed with the fixed clock offset or credential
+package resign
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/go-chassis/foundation/httpclient"
+)
+
+// Retrier inspects every response, the request sent is resp.Request.
+// it returns true if the request is rejected for a cause it has fixed, so that the request should be signed again
+type Retrier func(resp *http.Response) bool
+
+var (
+	retriers     []Retrier
+	retriersLock sync.RWMutex
+)
+
+// InstallRetrier adds a retrier, retriers are called in the order they are installed until one returns true
+func InstallRetrier(r Retrier) {
+	retriersLock.Lock()
+	defer retriersLock.Unlock()
+	retriers = append(retriers, r)
+}
+
+func shouldRetry(resp *http.Response) bool {
+	retriersLock.RLock()
+	defer retriersLock.RUnlock()
+	for _, r := range retriers {
+		if r(resp) {
+			return true
+		}
+	}
+	return false
+}
+
+// New creates a client like httpclient.New, but requests are signed by Transport instead of httpclient,
+// so that a retry is signed again. o.SignRequest signs requests, httpclient.SignRequest is used if it is nil
+func New(o *httpclient.Options) (*httpclient.Requests, error) {
+	opts := httpclient.Options{}
+	if o != nil {
+		opts = *o
+	}
+	sign := opts.SignRequest
+	opts.SignRequest = func(*http.Request) error { return nil }
+	c, err := httpclient.New(&opts)
+	if err != nil {
+		return nil, err
+	}
+	// httpclient sets TLS config only if the transport is a *http.Transport
+	if base, ok := c.Client.Transport.(*http.Transport); ok {
+		base.TLSClientConfig = opts.TLSConfig
+	}
+	c.Client.Transport = &Transport{Base: c.Client.Transport, Sign: sign}
+	return c, nil
+}
+
+// Transport signs a copy of each request before sending it, and retries once if a retrier asks to
+type Transport struct {
+	// Base sends requests, it is http.DefaultTransport if nil
+	Base http.RoundTripper
+	// Sign signs requests, httpclient.SignRequest is used if it is nil
+	Sign func(*http.Request) error
+}
+
+// RoundTrip implements http.RoundTripper, r is not modified, a signed copy of it is sent
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	req, err := t.sign(r, r.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Request == nil {
+		resp.Request = req
+	}
+	if !shouldRetry(resp) {
+		return resp, nil
+	}
+	body, ok := rewindBody(req)
+	if !ok {
+		return resp, nil
+	}
+	discardResponse(resp)
+	req, err = t.sign(r, body)
+	if err != nil {
+		return nil, err
+	}
+	return t.base().RoundTrip(req)
+}
+
+// sign signs a copy of r with body, the copy keeps the headers of r,
+// so a signing time set by caller is respected, and a retry is signed with the corrected clock
+func (t *Transport) sign(r *http.Request, body io.ReadCloser) (*http.Request, error) {
+	req := r.Clone(r.Context())
+	req.Body = body
+	sign := t.Sign
+	if sign == nil {
+		sign = httpclient.SignRequest
+	}
+	if sign == nil {
+		return req, nil
+	}
+	if err := sign(req); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return req, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// rewindBody returns the body to send again, a body can not be sent again without GetBody
+func rewindBody(req *http.Request) (io.ReadCloser, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req.Body, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// discardResponse reads the rest of body, so that the connection can be reused
+func discardResponse(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
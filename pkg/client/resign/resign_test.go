@@ -0,0 +1,106 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resign_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+	"github.com/stretchr/testify/assert"
+)
+
+// version is the credential version accepted by server, signed is the one used by client
+var version, signed int32
+
+func init() {
+	// the cause is fixed by signing with the version accepted by server
+	resign.InstallRetrier(func(resp *http.Response) bool {
+		if resp.Header.Get("X-Test-Fix") == "" || resp.Request.Header.Get("X-Test") != "resign" {
+			return false
+		}
+		atomic.StoreInt32(&signed, atomic.LoadInt32(&version))
+		return true
+	})
+}
+
+func TestNew(t *testing.T) {
+	var hits int32
+	atomic.StoreInt32(&version, 0)
+	atomic.StoreInt32(&signed, 0)
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.Header.Get("Authorization") != "v"+strconv.Itoa(int(atomic.LoadInt32(&version))) {
+			w.Header().Set("X-Test-Fix", "1")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	defer s.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	c, err := resign.New(&httpclient.Options{
+		TLSConfig: &tls.Config{RootCAs: pool},
+		SignRequest: func(r *http.Request) error {
+			r.Header.Set("Authorization", "v"+strconv.Itoa(int(atomic.LoadInt32(&signed))))
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	post := func() (int, string) {
+		h := http.Header{}
+		h.Set("X-Test", "resign")
+		resp, err := c.Post(context.Background(), s.URL, h, []byte("hello"))
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	t.Run("verify server by configured CA", func(t *testing.T) {
+		code, body := post()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "hello", body)
+		assert.Equal(t, int32(1), atomic.SwapInt32(&hits, 0))
+	})
+	t.Run("sign again and retry once", func(t *testing.T) {
+		atomic.StoreInt32(&version, 1)
+		code, body := post()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "hello", body, "body should be sent again")
+		assert.Equal(t, int32(2), atomic.SwapInt32(&hits, 0))
+	})
+	t.Run("do not retry if no retrier fixes the cause", func(t *testing.T) {
+		atomic.StoreInt32(&version, 2)
+		resp, err := c.Get(context.Background(), s.URL, nil)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.SwapInt32(&hits, 0))
+	})
+}
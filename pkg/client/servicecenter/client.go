@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package servicecenter implement client APIs of service center RBAC
+package servicecenter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/resign"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+)
+
+// DefaultTokenTTL is used when the token does not tell its expire time
+const DefaultTokenTTL = 30 * time.Minute
+
+// ErrUnauthorized means service center rejects the account
+var ErrUnauthorized = errors.New("service center: unauthorized")
+
+type Client struct {
+	c    *httpclient.Requests
+	opts Options
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("service center endpoint is empty")
+	}
+	signer := opts.Signer
+	if signer == nil {
+		// never fall back to the global sign func, it may be the one waiting for this client
+		signer = func(*http.Request) error { return nil }
+	}
+	c, err := resign.New(&httpclient.Options{
+		TLSConfig:   opts.TLSConfig,
+		SignRequest: signer,
+	})
+	return &Client{
+		c:    c,
+		opts: opts,
+	}, err
+}
+
+// GetToken logs in with account name and password
+func (c *Client) GetToken(name, pwd string) (*Token, error) {
+	body, err := json.Marshal(&account{Name: name, Password: pwd})
+	if err != nil {
+		return nil, err
+	}
+	h := http.Header{}
+	h.Set("Content-Type", "application/json;charset=utf8")
+	resp, err := c.c.Post(context.Background(), strings.TrimSuffix(c.opts.Endpoint, "/")+"/v4/token", h, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b := httputil.ReadBody(resp)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w, resp: %s", ErrUnauthorized, b)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s, resp: %s", resp.Status, b)
+	}
+	tr := &tokenResponse{}
+	if err := json.Unmarshal(b, tr); err != nil {
+		return nil, err
+	}
+	if tr.Token == "" {
+		return nil, errors.New("no token in response")
+	}
+	return &Token{Value: tr.Token, ExpiresAt: expiresAt(tr.Token)}, nil
+}
+
+// expiresAt reads exp claim of the JWT token without verifying it,
+// service center is the one to verify it
+func expiresAt(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		c := &claims{}
+		if err == nil && json.Unmarshal(b, c) == nil && c.Exp > 0 {
+			return time.Unix(c.Exp, 0)
+		}
+	}
+	return time.Now().Add(DefaultTokenTTL)
+}
@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package servicecenter_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/servicecenter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetToken(t *testing.T) {
+	exp := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	jwt := "eyJhbGciOiJSUzUxMiJ9." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"account":"root","exp":`+
+			strconv.FormatInt(exp.Unix(), 10)+`}`)) + ".sig"
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v4/token", r.URL.Path)
+		b, _ := ioutil.ReadAll(r.Body)
+		req := make(map[string]string)
+		assert.NoError(t, json.Unmarshal(b, &req))
+		if req["name"] != "root" || req["password"] != "pwd" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token":"` + jwt + `"}`))
+	}))
+	defer s.Close()
+
+	c, err := servicecenter.New(servicecenter.Options{Endpoint: s.URL})
+	assert.NoError(t, err)
+	tk, err := c.GetToken("root", "pwd")
+	assert.NoError(t, err)
+	assert.Equal(t, jwt, tk.Value)
+	assert.True(t, exp.Equal(tk.ExpiresAt))
+
+	_, err = c.GetToken("root", "wrong")
+	assert.True(t, errors.Is(err, servicecenter.ErrUnauthorized))
+
+	_, err = servicecenter.New(servicecenter.Options{})
+	assert.Error(t, err)
+}
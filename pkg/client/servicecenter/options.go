@@ -0,0 +1,33 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package servicecenter
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+type Options struct {
+	// Endpoint is the address of service center, like https://127.0.0.1:30100
+	Endpoint string
+	// Signer signs requests, if it is nil, no auth info is added
+	Signer func(*http.Request) error
+	// TLSConfig verifies service center, system CAs are used if it is nil.
+	// never skip verification in production, account name and password are sent to service center
+	TLSConfig *tls.Config
+}
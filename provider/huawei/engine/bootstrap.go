@@ -19,6 +19,8 @@ package engine
 
 import (
 	"errors"
+	"strings"
+
 	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis-cloud/auth"
 	"github.com/go-chassis/go-chassis-cloud/pkg/client/cse"
@@ -28,8 +30,13 @@ import (
 	"github.com/go-chassis/openlog"
 )
 
-//Init fetch endpoints from engine manager
+//Init fetch endpoints from engine manager,
+//errors are redacted, because they may carry the credential in response of cloud services
 func Init() error {
+	return auth.RedactError(initEngine())
+}
+
+func initEngine() error {
 	if err := auth.LoadAuth(); err != nil {
 		return err
 	}
@@ -58,6 +65,10 @@ func Init() error {
 			"config":    config.GlobalDefinition.ServiceComb.Config.Client.ServerURI,
 			"dashboard": config.GlobalDefinition.ServiceComb.Monitor.Client.ServerURI,
 		}))
+	if strings.EqualFold(md.CSE.AuthType, auth.TypeRBAC) {
+		openlog.Info("engine " + name + " enables RBAC, login service center")
+		return auth.LoadRBACAuth()
+	}
 	return nil
 }
 func init() {
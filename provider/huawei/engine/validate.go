@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/cse"
+	"github.com/go-chassis/go-chassis-cloud/provider/huawei/env"
+)
+
+// validateByEngineManager gets metadata of the engine with a request signed by ShaAKSK of c,
+// so that credential of CSE on premises, where there is no IAM, is validated as well.
+// engine manager is verified by auth.TLSConfig, the signed request is not sent to an unverified server
+func validateByEngineManager(c *auth.Credential, sk string) error {
+	if env.EngineManagerAddr() == "" {
+		return errors.New("engine manager address must be set to validate credential by it")
+	}
+	sign, err := auth.GetTemporaryShaAKSKSignFunc(c.AccessKey, sk, c.SecurityToken, c.Project)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := auth.TLSConfig()
+	if err != nil {
+		return err
+	}
+	client, err := cse.New(cse.Options{Endpoint: env.EngineManagerAddr(), Signer: sign, TLSConfig: tlsConfig})
+	if err != nil {
+		return err
+	}
+	_, err = client.GetEngineMD(archaius.GetString("servicecomb.engine.name", "default"))
+	if errors.Is(err, cse.ErrUnauthorized) {
+		return fmt.Errorf("%w: ak %s, %v", auth.ErrCredentialRejected, auth.MaskSecret(c.AccessKey), err)
+	}
+	return err
+}
+
+func init() {
+	auth.InstallCredentialValidator(auth.ValidateTargetEngineManager, validateByEngineManager)
+}
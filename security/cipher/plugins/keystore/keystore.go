@@ -0,0 +1,286 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keystore is a cipher plugin with a root key and work key hierarchy stored under ${CIPHER_ROOT}/keystore.
+// the root key is derived from key components and salt, it only encrypts the work key,
+// and the work key encrypts secrets with AES-GCM.
+// import it and set akskCustomCipher to "keystore" to decrypt sk in certificate.yaml
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/cari/security"
+	chassiscipher "github.com/go-chassis/go-chassis/v2/security/cipher"
+	"github.com/go-chassis/openlog"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Name is the cipher plugin name used in akskCustomCipher
+const Name = "keystore"
+
+// DirName is the keystore directory under ${CIPHER_ROOT}
+const DirName = "keystore"
+
+// files in keystore directory
+const (
+	RootDir     = "root"
+	WorkKeyFile = "work.key"
+	SaltFile    = "salt"
+
+	componentCount = 2
+	keySize        = 32
+	saltSize       = 16
+	// iterations of PBKDF2 deriving root key from components
+	iterations = 10000
+)
+
+// errors of keystore
+var (
+	ErrKeystoreExist    = errors.New("keystore already exists")
+	ErrInvalidComponent = errors.New("invalid root key component")
+	ErrInvalidCipher    = errors.New("invalid cipher text")
+)
+
+var (
+	loaded     = make(map[string]*loadedCipher)
+	loadedLock sync.Mutex
+)
+
+// loadedCipher is a cached keystore, it is valid as long as the work key file is not changed
+type loadedCipher struct {
+	c       *Cipher
+	modTime time.Time
+	size    int64
+}
+
+// Cipher encrypts and decrypts with the work key
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// Dir returns ${CIPHER_ROOT}/keystore
+func Dir() string {
+	return filepath.Join(os.Getenv("CIPHER_ROOT"), DirName)
+}
+
+// Generate creates random root key components, salt and work key in dir,
+// it never overwrites an existing keystore, because secrets encrypted by it can not be decrypted any more
+func Generate(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, WorkKeyFile)); err == nil {
+		return ErrKeystoreExist
+	}
+	if err := os.MkdirAll(filepath.Join(dir, RootDir), 0700); err != nil {
+		return err
+	}
+	for i := 1; i <= componentCount; i++ {
+		if err := writeRandom(componentPath(dir, i), keySize); err != nil {
+			return err
+		}
+	}
+	if err := writeRandom(filepath.Join(dir, RootDir, SaltFile), saltSize); err != nil {
+		return err
+	}
+	root, err := rootKey(dir)
+	if err != nil {
+		return err
+	}
+	work := make([]byte, keySize)
+	if _, err := rand.Read(work); err != nil {
+		return err
+	}
+	encrypted, err := seal(root, work)
+	if err != nil {
+		return err
+	}
+	Invalidate(dir)
+	return ioutil.WriteFile(filepath.Join(dir, WorkKeyFile), []byte(encrypted), 0600)
+}
+
+// Load decrypts work key with root key, a loaded keystore is cached until the work key file changes,
+// so that a regenerated keystore is used without restart
+func Load(dir string) (*Cipher, error) {
+	loadedLock.Lock()
+	defer loadedLock.Unlock()
+	name := filepath.Join(dir, WorkKeyFile)
+	info, err := os.Stat(name)
+	if err != nil {
+		delete(loaded, dir)
+		return nil, err
+	}
+	if l, ok := loaded[dir]; ok && l.modTime.Equal(info.ModTime()) && l.size == info.Size() {
+		return l.c, nil
+	}
+	root, err := rootKey(dir)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	work, err := open(root, strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt work key failed: %w", err)
+	}
+	aead, err := newAEAD(work)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cipher{aead: aead}
+	loaded[dir] = &loadedCipher{c: c, modTime: info.ModTime(), size: info.Size()}
+	return c, nil
+}
+
+// Invalidate drops the cached keystore of dir, the next Load reads it again.
+// it is needed only if the keystore is replaced by files with the same mod time and size
+func Invalidate(dir string) {
+	loadedLock.Lock()
+	delete(loaded, dir)
+	loadedLock.Unlock()
+}
+
+// Encrypt returns base64 encoded nonce and cipher text
+func (c *Cipher) Encrypt(src string) (string, error) {
+	return sealWith(c.aead, []byte(src))
+}
+
+// Decrypt decrypts the result of Encrypt
+func (c *Cipher) Decrypt(src string) (string, error) {
+	b, err := openWith(c.aead, src)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func componentPath(dir string, i int) string {
+	return filepath.Join(dir, RootDir, fmt.Sprintf("component%d", i))
+}
+
+func writeRandom(name string, size int) error {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, []byte(hex.EncodeToString(b)), 0600)
+}
+
+func readHex(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(b)))
+}
+
+// rootKey xors all components, and derives root key from the result with salt
+func rootKey(dir string) ([]byte, error) {
+	material := make([]byte, keySize)
+	for i := 1; i <= componentCount; i++ {
+		c, err := readHex(componentPath(dir, i))
+		if err != nil {
+			return nil, err
+		}
+		if len(c) != keySize {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidComponent, componentPath(dir, i))
+		}
+		for j := range material {
+			material[j] ^= c[j]
+		}
+	}
+	salt, err := readHex(filepath.Join(dir, RootDir, SaltFile))
+	if err != nil {
+		return nil, err
+	}
+	return pbkdf2Key(material, salt, iterations, keySize), nil
+}
+
+// pbkdf2Key is PBKDF2 with HMAC-SHA256 in RFC 8018
+func pbkdf2Key(password, salt []byte, iter, keyLen int) []byte {
+	return pbkdf2.Key(password, salt, iter, keyLen, sha256.New)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plain []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	return sealWith(aead, plain)
+}
+
+func open(key []byte, src string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return openWith(aead, src)
+}
+
+func sealWith(aead cipher.AEAD, plain []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+func openWith(aead cipher.AEAD, src string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(src)
+	if err != nil || len(b) < aead.NonceSize() {
+		return nil, ErrInvalidCipher
+	}
+	plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrInvalidCipher
+	}
+	return plain, nil
+}
+
+func newCipher() security.Cipher {
+	c, err := Load(Dir())
+	if err != nil {
+		openlog.Error("load keystore failed: " + err.Error())
+		return nil
+	}
+	return c
+}
+
+func init() {
+	chassiscipher.InstallCipherPlugin(Name, newCipher)
+}
@@ -0,0 +1,133 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keystore_test
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis-cloud/security/cipher/plugins/keystore"
+	"github.com/go-chassis/go-chassis/v2/security/cipher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeystore(t *testing.T) {
+	root, err := ioutil.TempDir("", "cipher")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	os.Setenv("CIPHER_ROOT", root)
+	defer os.Unsetenv("CIPHER_ROOT")
+	dir := keystore.Dir()
+	assert.Equal(t, filepath.Join(root, keystore.DirName), dir)
+
+	assert.NoError(t, keystore.Generate(dir))
+	assert.Equal(t, keystore.ErrKeystoreExist, keystore.Generate(dir), "never overwrite keystore")
+
+	c, err := keystore.Load(dir)
+	assert.NoError(t, err)
+	encrypted, err := c.Encrypt("secret key")
+	assert.NoError(t, err)
+	assert.NotContains(t, encrypted, "secret key")
+	another, err := c.Encrypt("secret key")
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, another, "random nonce")
+
+	t.Run("plugin", func(t *testing.T) {
+		f, err := cipher.GetCipherNewFunc(keystore.Name)
+		assert.NoError(t, err)
+		plugin := f()
+		assert.NotNil(t, plugin)
+		plain, err := plugin.Decrypt(encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret key", plain)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		_, err := c.Decrypt(encrypted[:len(encrypted)-4] + "AAA=")
+		assert.Equal(t, keystore.ErrInvalidCipher, err)
+		_, err = c.Decrypt("not base64")
+		assert.Equal(t, keystore.ErrInvalidCipher, err)
+	})
+	t.Run("wrong root key", func(t *testing.T) {
+		other := filepath.Join(root, "other")
+		assert.NoError(t, keystore.Generate(other))
+		work, err := ioutil.ReadFile(filepath.Join(dir, keystore.WorkKeyFile))
+		assert.NoError(t, err)
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(other, keystore.WorkKeyFile), work, 0600))
+		_, err = keystore.Load(other)
+		assert.Error(t, err)
+	})
+	t.Run("regenerated", func(t *testing.T) {
+		other := filepath.Join(root, "regenerated")
+		assert.NoError(t, keystore.Generate(other))
+		oc, err := keystore.Load(other)
+		assert.NoError(t, err)
+		otherEncrypted, err := oc.Encrypt("another secret key")
+		assert.NoError(t, err)
+
+		// replace the keystore in place, as an operator regenerating it does
+		for _, name := range []string{
+			filepath.Join(keystore.RootDir, "component1"),
+			filepath.Join(keystore.RootDir, "component2"),
+			filepath.Join(keystore.RootDir, keystore.SaltFile),
+			keystore.WorkKeyFile,
+		} {
+			b, err := ioutil.ReadFile(filepath.Join(other, name))
+			assert.NoError(t, err)
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), b, 0600))
+		}
+		later := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(filepath.Join(dir, keystore.WorkKeyFile), later, later))
+
+		reloaded, err := keystore.Load(dir)
+		assert.NoError(t, err)
+		plain, err := reloaded.Decrypt(otherEncrypted)
+		assert.NoError(t, err, "work key is reloaded without restart")
+		assert.Equal(t, "another secret key", plain)
+		_, err = reloaded.Decrypt(encrypted)
+		assert.Equal(t, keystore.ErrInvalidCipher, err)
+
+		keystore.Invalidate(dir)
+		again, err := keystore.Load(dir)
+		assert.NoError(t, err)
+		assert.NotSame(t, reloaded, again)
+	})
+}
+
+func TestPBKDF2(t *testing.T) {
+	// PBKDF2-HMAC-SHA256 test vectors of RFC 7914 section 11
+	cases := []struct {
+		password, salt string
+		iter           int
+		expected       string
+	}{
+		{"passwd", "salt", 1,
+			"55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+				"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000,
+			"4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56" +
+				"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, c := range cases {
+		dk := keystore.PBKDF2([]byte(c.password), []byte(c.salt), c.iter, 64)
+		assert.Equal(t, c.expected, hex.EncodeToString(dk), c.password)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kms is a cipher plugin of envelope encryption with huawei cloud KMS.
+// a secret is encrypted by a data key, and the data key is encrypted by a KMS master key,
+// import it and set akskCustomCipher to "kms" to decrypt sk, or use it to decrypt any config value.
+// KMS requests are signed with a bootstrap credential, which must not be encrypted by KMS itself
+package kms
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/cari/security"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/kms"
+	chassiscipher "github.com/go-chassis/go-chassis/v2/security/cipher"
+)
+
+// Name is the cipher plugin name used in akskCustomCipher
+const Name = "kms"
+
+// DefaultCacheTTL is how long a plain data key is cached in memory
+const DefaultCacheTTL = 10 * time.Minute
+
+const (
+	keyEndpoint  = "servicecomb.kms.endpoint"
+	keyProjectID = "servicecomb.kms.projectID"
+	keyKeyID     = "servicecomb.kms.keyID"
+	keyProviders = "servicecomb.kms.providers"
+	keyCacheTTL  = "servicecomb.kms.cacheTTL"
+
+	// separator of key id, encrypted data key and encrypted data
+	separator = ":"
+)
+
+// errors of kms cipher
+var (
+	ErrInvalidCipher      = errors.New("invalid kms cipher text")
+	ErrEncryptedBootstrap = errors.New("bootstrap credential of kms can not be encrypted by kms")
+)
+
+var defaultCipher = &Cipher{cache: make(map[string]*cachedDataKey)}
+
+type cachedDataKey struct {
+	aead      cipher.AEAD
+	expiresAt time.Time
+}
+
+// Cipher encrypts secret as "key_id:encrypted_data_key:base64(nonce+encrypted_data)",
+// plain data keys are cached until TTL
+type Cipher struct {
+	mu    sync.Mutex
+	cache map[string]*cachedDataKey
+}
+
+// Encrypt creates a data key with master key servicecomb.kms.keyID and encrypts src with it
+func (c *Cipher) Encrypt(src string) (string, error) {
+	keyID := archaius.GetString(keyKeyID, "")
+	if keyID == "" {
+		return "", errors.New(keyKeyID + " is empty")
+	}
+	client, err := newClient()
+	if err != nil {
+		return "", err
+	}
+	dk, err := client.CreateDataKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dk.Plain)
+	if err != nil {
+		return "", err
+	}
+	c.put(keyID, dk.CipherText, aead)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(src), nil))
+	return strings.Join([]string{keyID, dk.CipherText, data}, separator), nil
+}
+
+// Decrypt decrypts data key with KMS decrypt-datakey, and decrypts src with the data key
+func (c *Cipher) Decrypt(src string) (string, error) {
+	parts := strings.Split(src, separator)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return "", ErrInvalidCipher
+	}
+	aead, err := c.dataKey(parts[0], parts[1])
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil || len(b) < aead.NonceSize() {
+		return "", ErrInvalidCipher
+	}
+	plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidCipher
+	}
+	return string(plain), nil
+}
+
+func (c *Cipher) dataKey(keyID, cipherText string) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if dk, ok := c.cache[keyID+separator+cipherText]; ok && time.Now().Before(dk.expiresAt) {
+		return dk.aead, nil
+	}
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := client.DecryptDataKey(keyID, cipherText)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(plain)
+	if err != nil {
+		return nil, err
+	}
+	c.putLocked(keyID, cipherText, aead)
+	return aead, nil
+}
+
+func (c *Cipher) put(keyID, cipherText string, aead cipher.AEAD) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.putLocked(keyID, cipherText, aead)
+}
+
+func (c *Cipher) putLocked(keyID, cipherText string, aead cipher.AEAD) {
+	now := time.Now()
+	for k, dk := range c.cache {
+		if !now.Before(dk.expiresAt) {
+			delete(c.cache, k)
+		}
+	}
+	c.cache[keyID+separator+cipherText] = &cachedDataKey{aead: aead, expiresAt: now.Add(cacheTTL())}
+}
+
+func cacheTTL() time.Duration {
+	d, err := time.ParseDuration(archaius.GetString(keyCacheTTL, ""))
+	if err != nil || d <= 0 {
+		return DefaultCacheTTL
+	}
+	return d
+}
+
+// newClient creates KMS client signed by bootstrap credential of servicecomb.kms.providers
+func newClient() (*kms.Client, error) {
+	chain := auth.DefaultBootstrapProviders
+	if v := archaius.GetString(keyProviders, ""); v != "" {
+		chain = strings.Split(v, ",")
+		for i := range chain {
+			chain[i] = strings.TrimSpace(chain[i])
+		}
+	}
+	cred, provider, err := auth.RetrieveCredential(chain...)
+	if err != nil {
+		return nil, fmt.Errorf("no bootstrap credential for kms: %w", err)
+	}
+	if cred.AkskCustomCipher == Name {
+		return nil, ErrEncryptedBootstrap
+	}
+	sk, err := cred.PlainSecretKey()
+	if err != nil {
+		return nil, fmt.Errorf("bootstrap credential of provider [%s]: %w", provider, err)
+	}
+	sign, err := auth.GetAPIGSignFunc(cred.AccessKey, sk, cred.SecurityToken)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := auth.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kms.New(kms.Options{
+		Endpoint:  archaius.GetString(keyEndpoint, ""),
+		ProjectID: archaius.GetString(keyProjectID, ""),
+		Signer:    sign,
+		TLSConfig: tlsConfig,
+	})
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func newCipher() security.Cipher {
+	return defaultCipher
+}
+
+func init() {
+	chassiscipher.InstallCipherPlugin(Name, newCipher)
+}
@@ -0,0 +1,116 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kms_test
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/foundation/httpclient"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis-cloud/auth"
+	"github.com/go-chassis/go-chassis-cloud/pkg/client/kms/kmstest"
+	"github.com/go-chassis/go-chassis-cloud/security/cipher/plugins/kms"
+	"github.com/go-chassis/go-chassis/v2/security/cipher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	s := kmstest.NewServer("key1")
+	defer s.Close()
+	s.Authorize = func(r *http.Request) bool {
+		return strings.HasPrefix(r.Header.Get("Authorization"), auth.APIGAlgorithm+" Access=bootstrap-ak,")
+	}
+	os.Setenv(auth.EnvAccessKey, "bootstrap-ak")
+	os.Setenv(auth.EnvSecretKey, "bootstrap-sk")
+	defer os.Unsetenv(auth.EnvAccessKey)
+	defer os.Unsetenv(auth.EnvSecretKey)
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	for k, v := range map[string]string{
+		"servicecomb.kms.endpoint":  s.URL,
+		"servicecomb.kms.projectID": "pid",
+		"servicecomb.kms.keyID":     "key1",
+		"servicecomb.kms.cacheTTL":  "1ms",
+	} {
+		assert.NoError(t, archaius.Set(k, v))
+	}
+	f, err := cipher.GetCipherNewFunc(kms.Name)
+	assert.NoError(t, err)
+	c := f()
+
+	encrypted, err := c.Encrypt("service-sk")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "key1:"))
+	assert.NotContains(t, encrypted, "service-sk")
+
+	t.Run("decrypt data key with kms, and cache it", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, archaius.Set("servicecomb.kms.cacheTTL", "1h"))
+		for i := 0; i < 3; i++ {
+			plain, err := c.Decrypt(encrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, "service-sk", plain)
+		}
+		assert.Equal(t, 1, s.Decrypts())
+	})
+	t.Run("decrypt sk of service credential", func(t *testing.T) {
+		for k, v := range map[string]string{
+			"servicecomb.credentials.providers":        auth.ProviderArchaius,
+			"servicecomb.credentials.accessKey":        "service-ak",
+			"servicecomb.credentials.secretKey":        encrypted,
+			"servicecomb.credentials.akskCustomCipher": kms.Name,
+			"servicecomb.credentials.project":          "p",
+		} {
+			assert.NoError(t, archaius.Set(k, v))
+		}
+		assert.NoError(t, auth.LoadAkskAuth())
+		r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+		assert.NoError(t, httpclient.SignRequest(r))
+		v, err := auth.NewVerifier(&auth.Credential{AccessKey: "service-ak", SecretKey: "service-sk"})
+		assert.NoError(t, err)
+		_, err = v.VerifyHeaders(map[string]string{
+			auth.HeaderServiceAk:      r.Header.Get(auth.HeaderServiceAk),
+			auth.HeaderServiceShaAKSK: r.Header.Get(auth.HeaderServiceShaAKSK),
+			auth.HeaderServiceProject: r.Header.Get(auth.HeaderServiceProject),
+		})
+		assert.NoError(t, err)
+	})
+	t.Run("bootstrap credential does not inherit cipher of service credential", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("servicecomb.kms.cacheTTL", "1ms"))
+		encrypted, err := c.Encrypt("another-sk")
+		assert.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+		plain, err := c.Decrypt(encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "another-sk", plain)
+		assert.Equal(t, 2, s.Decrypts())
+	})
+	t.Run("invalid cipher text", func(t *testing.T) {
+		_, err := c.Decrypt("not kms cipher")
+		assert.Equal(t, kms.ErrInvalidCipher, err)
+	})
+	t.Run("bootstrap credential encrypted by kms", func(t *testing.T) {
+		os.Setenv(auth.EnvAkskCustomCipher, kms.Name)
+		defer os.Unsetenv(auth.EnvAkskCustomCipher)
+		_, err := c.Decrypt("key1:00:AAAA")
+		assert.Equal(t, kms.ErrEncryptedBootstrap, err)
+	})
+}